@@ -17,10 +17,13 @@ package patch
 import (
 	"archive/zip"
 	"bytes"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"io/ioutil"
 	"net/http"
 
+	"emperror.dev/errors"
 	json "github.com/json-iterator/go"
 
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -29,18 +32,54 @@ import (
 
 const LastAppliedConfig = "banzaicloud.com/last-applied"
 
+const hashPrefix = "sha256:"
+
 var DefaultAnnotator = NewAnnotator(LastAppliedConfig)
 
 type Annotator struct {
 	metadataAccessor meta.MetadataAccessor
 	key              string
+
+	excludedPaths []fieldPath
+	hashedPaths   []fieldPath
+
+	historyLimit    int
+	historyMaxBytes int
+
+	hashLabel string
+}
+
+type AnnotatorOption func(*Annotator)
+
+// WithExcludedPaths leaves the given fields out of the recorded original configuration.
+// Paths are dot separated, "[]" selects every element of a list, e.g. "spec.versions[].schema".
+// Excluded fields are expected to be owned by the caller: when a patch is calculated
+// their current value is considered to be the last applied one, so dropping them
+// from the modified object removes them remotely. It panics if a path is invalid.
+func WithExcludedPaths(paths ...string) AnnotatorOption {
+	return func(a *Annotator) {
+		a.excludedPaths = append(a.excludedPaths, mustParseFieldPaths(paths)...)
+	}
+}
+
+// WithHashedPaths records a hash of the given fields instead of their value.
+// Unlike excluded fields, hashed fields are only removed remotely if they were
+// present in the last applied configuration. It panics if a path is invalid.
+func WithHashedPaths(paths ...string) AnnotatorOption {
+	return func(a *Annotator) {
+		a.hashedPaths = append(a.hashedPaths, mustParseFieldPaths(paths)...)
+	}
 }
 
-func NewAnnotator(key string) *Annotator {
-	return &Annotator{
+func NewAnnotator(key string, opts ...AnnotatorOption) *Annotator {
+	a := &Annotator{
 		key:              key,
 		metadataAccessor: meta.NewAccessor(),
 	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
 }
 
 // GetOriginalConfiguration retrieves the original configuration of the object
@@ -60,15 +99,7 @@ func (a *Annotator) GetOriginalConfiguration(obj runtime.Object) ([]byte, error)
 		return nil, nil
 	}
 
-	// Try to base64 decode, and fallback to non-base64 encoded content for backwards compatibility.
-	if decoded, err := base64.StdEncoding.DecodeString(original); err == nil {
-		if http.DetectContentType(decoded) == "application/zip" {
-			return unZipAnnotation(decoded)
-		}
-		return decoded, nil
-	}
-
-	return []byte(original), nil
+	return decodeAnnotation(original)
 }
 
 // SetOriginalConfiguration sets the original configuration of the object
@@ -116,6 +147,10 @@ func (a *Annotator) GetModifiedConfiguration(obj runtime.Object, annotate bool)
 
 	original := annots[a.key]
 	delete(annots, a.key)
+	history, hasHistory := annots[a.historyKey()]
+	if hasHistory {
+		delete(annots, a.historyKey())
+	}
 	if err := a.metadataAccessor.SetAnnotations(obj, annots); err != nil {
 		return nil, err
 	}
@@ -125,13 +160,40 @@ func (a *Annotator) GetModifiedConfiguration(obj runtime.Object, annotate bool)
 		a.metadataAccessor.SetAnnotations(obj, nil)
 	}
 
+	if a.hashLabel != "" {
+		labels, err := a.metadataAccessor.Labels(obj)
+		if err != nil {
+			return nil, err
+		}
+		if hash, ok := labels[a.hashLabel]; ok {
+			delete(labels, a.hashLabel)
+			if len(labels) == 0 {
+				labels = nil
+			}
+			if err := a.metadataAccessor.SetLabels(obj, labels); err != nil {
+				return nil, err
+			}
+			defer func() {
+				if labels == nil {
+					labels = map[string]string{}
+				}
+				labels[a.hashLabel] = hash
+				a.metadataAccessor.SetLabels(obj, labels)
+			}()
+		}
+	}
+
 	modified, err = json.ConfigCompatibleWithStandardLibrary.Marshal(obj)
 	if err != nil {
 		return nil, err
 	}
 
 	if annotate {
-		annots[a.key], err = zipAndBase64EncodeAnnotation(modified)
+		stripped, err := a.stripOriginal(modified)
+		if err != nil {
+			return nil, err
+		}
+		annots[a.key], err = zipAndBase64EncodeAnnotation(stripped)
 		if err != nil {
 			return nil, err
 		}
@@ -147,6 +209,9 @@ func (a *Annotator) GetModifiedConfiguration(obj runtime.Object, annotate bool)
 
 	// Restore the object to its original condition.
 	annots[a.key] = original
+	if hasHistory {
+		annots[a.historyKey()] = history
+	}
 	if err := a.metadataAccessor.SetAnnotations(obj, annots); err != nil {
 		return nil, err
 	}
@@ -162,11 +227,119 @@ func (a *Annotator) SetLastAppliedAnnotation(obj runtime.Object) error {
 		return err
 	}
 	// Remove nulls from json
-	modifiedWithoutNulls, _, err := DeleteNullInJson(modified)
+	modifiedWithoutNulls, err := deleteNullInJsonBytes(modified)
+	if err != nil {
+		return err
+	}
+	if err := a.setConfigHashLabel(obj, modifiedWithoutNulls); err != nil {
+		return err
+	}
+	stripped, err := a.stripOriginal(modifiedWithoutNulls)
 	if err != nil {
 		return err
 	}
-	return a.SetOriginalConfiguration(obj, modifiedWithoutNulls)
+	return a.SetOriginalConfiguration(obj, stripped)
+}
+
+// stripOriginal removes the excluded fields from the configuration
+// and replaces the hashed ones with the hash of their value.
+func (a *Annotator) stripOriginal(original []byte) ([]byte, error) {
+	if len(a.excludedPaths) == 0 && len(a.hashedPaths) == 0 {
+		return original, nil
+	}
+
+	var originalMap map[string]interface{}
+	if err := unmarshalWithNumbers(original, &originalMap); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal original configuration")
+	}
+
+	for _, p := range a.excludedPaths {
+		p.delete(originalMap)
+	}
+
+	var err error
+	for _, p := range a.hashedPaths {
+		p.visit(originalMap, func(parent map[string]interface{}, key string) {
+			value, ok := parent[key]
+			if !ok || err != nil {
+				return
+			}
+			parent[key], err = hashValue(value)
+		})
+		if err != nil {
+			return nil, errors.WrapWithDetails(err, "could not hash field", "path", p.String())
+		}
+	}
+
+	return json.ConfigCompatibleWithStandardLibrary.Marshal(originalMap)
+}
+
+// restoreOriginal fills in the fields of the original configuration that were
+// excluded or hashed when it was recorded, using their value in current.
+func (a *Annotator) restoreOriginal(original, current []byte) ([]byte, error) {
+	if original == nil || (len(a.excludedPaths) == 0 && len(a.hashedPaths) == 0) {
+		return original, nil
+	}
+
+	var originalMap, currentMap map[string]interface{}
+	if err := unmarshalWithNumbers(original, &originalMap); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal original configuration")
+	}
+	if err := unmarshalWithNumbers(current, &currentMap); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal current configuration")
+	}
+
+	for _, p := range a.excludedPaths {
+		p.restore(originalMap, currentMap, func(original, current map[string]interface{}, key string) {
+			if value, ok := current[key]; ok {
+				original[key] = value
+			}
+		})
+	}
+
+	for _, p := range a.hashedPaths {
+		p.restore(originalMap, currentMap, func(original, current map[string]interface{}, key string) {
+			if _, ok := original[key]; !ok {
+				return
+			}
+			// The field was applied by us, so whatever it is set to now is ours to change or remove.
+			if value, ok := current[key]; ok {
+				original[key] = value
+			} else {
+				delete(original, key)
+			}
+		})
+	}
+
+	return json.ConfigCompatibleWithStandardLibrary.Marshal(originalMap)
+}
+
+func hashValue(value interface{}) (string, error) {
+	// Map keys are sorted during marshaling, so the hash is stable.
+	data, err := json.ConfigCompatibleWithStandardLibrary.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hashPrefix + hex.EncodeToString(sum[:]), nil
+}
+
+func decodeAnnotation(value string) ([]byte, error) {
+	decoded, _, err := decodeAnnotationWithFormat(value)
+	return decoded, err
+}
+
+func decodeAnnotationWithFormat(value string) ([]byte, AnnotationFormat, error) {
+	// Try to base64 decode, and fallback to non-base64 encoded content for backwards compatibility.
+	if decoded, err := base64.StdEncoding.DecodeString(value); err == nil {
+		if http.DetectContentType(decoded) == "application/zip" {
+			unzipped, err := unZipAnnotation(decoded)
+			return unzipped, AnnotationFormatZip, err
+		}
+		return decoded, AnnotationFormatBase64, nil
+	}
+
+	return []byte(value), AnnotationFormatRaw, nil
 }
 
 func zipAndBase64EncodeAnnotation(original []byte) (string, error) {
@@ -15,6 +15,9 @@
 package patch
 
 import (
+	"encoding/base64"
+	"reflect"
+	"strings"
 	"testing"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -33,3 +36,132 @@ func TestAnnotationRemovedWhenEmpty(t *testing.T) {
 		t.Fatalf("Expected {\"metadata\":{} got %s", string(modified))
 	}
 }
+
+func TestExcludedAndHashedPaths(t *testing.T) {
+	annotator := NewAnnotator(LastAppliedConfig,
+		WithExcludedPaths("binaryData"),
+		WithHashedPaths("data.script", "data.missing"))
+	patchMaker := NewPatchMaker(annotator, &K8sStrategicMergePatcher{}, &BaseJSONMergePatcher{})
+
+	newConfigMap := func() *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   map[string]interface{}{"name": "test"},
+			"data":       map[string]interface{}{"script": "echo hello", "key": "value"},
+			"binaryData": map[string]interface{}{"blob": "AAAA"},
+		}}
+	}
+
+	current := newConfigMap()
+	if err := annotator.SetLastAppliedAnnotation(current); err != nil {
+		t.Fatal(err)
+	}
+	original, err := annotator.GetOriginalConfiguration(current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	originalMap := mustToUnstructured(original)
+	if _, ok := originalMap["binaryData"]; ok {
+		t.Fatalf("Expected binaryData to be excluded, got %s", original)
+	}
+	script, _, _ := unstructured.NestedString(originalMap, "data", "script")
+	if !strings.HasPrefix(script, hashPrefix) {
+		t.Fatalf("Expected data.script to be hashed, got %s", original)
+	}
+
+	result, err := patchMaker.Calculate(current, newConfigMap())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.IsEmpty() {
+		t.Fatalf("Expected empty patch, got %s", result.Patch)
+	}
+
+	modified := newConfigMap()
+	unstructured.RemoveNestedField(modified.Object, "binaryData")
+	unstructured.RemoveNestedField(modified.Object, "data", "script")
+	result, err = patchMaker.Calculate(current, modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"binaryData": nil,
+		"data":       map[string]interface{}{"script": nil},
+	}
+	if !reflect.DeepEqual(mustToUnstructured(result.Patch), want) {
+		t.Fatalf("Expected patch %v, got %s", want, result.Patch)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	zipped, err := zipAndBase64EncodeAnnotation([]byte(`{"kind":"ConfigMap"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		annotation string
+		wantFormat AnnotationFormat
+	}{
+		{name: "zip", annotation: zipped, wantFormat: AnnotationFormatZip},
+		{name: "base64", annotation: base64.StdEncoding.EncodeToString([]byte(`{"kind":"ConfigMap"}`)), wantFormat: AnnotationFormatBase64},
+		{name: "raw", annotation: `{"kind":"ConfigMap"}`, wantFormat: AnnotationFormatRaw},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := unstructured.Unstructured{}
+			u.SetAnnotations(map[string]string{LastAppliedConfig: tt.annotation})
+			info, err := DefaultAnnotator.Inspect(&u)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Format != tt.wantFormat {
+				t.Errorf("Inspect() format = %s, want %s", info.Format, tt.wantFormat)
+			}
+			if info.EncodedSize != len(tt.annotation) || info.DecodedSize != len(`{"kind":"ConfigMap"}`) {
+				t.Errorf("Inspect() sizes = %d/%d", info.EncodedSize, info.DecodedSize)
+			}
+			if want := "{\n  \"kind\": \"ConfigMap\"\n}"; info.Original != want {
+				t.Errorf("Inspect() original = %q, want %q", info.Original, want)
+			}
+		})
+	}
+
+	info, err := DefaultAnnotator.Inspect(&unstructured.Unstructured{})
+	if err != nil || info != nil {
+		t.Errorf("Inspect() without annotation = %v, %v", info, err)
+	}
+}
+
+func TestLargeIntegersArePreserved(t *testing.T) {
+	const configuration = `{"data":{"id":9007199254740993,"ids":[9007199254740993,1.5]},"spec":{"script":"echo hello"}}`
+
+	normalized, normalizedMap, err := DeleteNullInJson([]byte(configuration))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(normalized) != configuration {
+		t.Fatalf("Expected %s, got %s", configuration, normalized)
+	}
+	if _, ok := normalizedMap["data"].(map[string]interface{})["id"].(float64); !ok {
+		t.Fatalf("Expected numbers of the returned map to be float64, got %#v", normalizedMap)
+	}
+
+	annotator := NewAnnotator(LastAppliedConfig, WithExcludedPaths("metadata.annotations"), WithHashedPaths("spec.script"))
+	stripped, err := annotator.stripOriginal([]byte(configuration))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(stripped), "9007199254740993,1.5]") {
+		t.Fatalf("Expected the integers to be kept, got %s", stripped)
+	}
+	restored, err := annotator.restoreOriginal(stripped, []byte(configuration))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(restored) != configuration {
+		t.Fatalf("Expected %s, got %s", configuration, restored)
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"emperror.dev/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// BatchItem is a pair of objects to calculate the patch for in CalculateBatch.
+type BatchItem struct {
+	Current  runtime.Object
+	Modified runtime.Object
+	Options  []CalculateOption
+}
+
+// ObjectKey identifies the object of a BatchItem. The kind of typed objects without
+// type information is the name of their Go type.
+type ObjectKey struct {
+	schema.GroupVersionKind
+	Namespace string
+	Name      string
+}
+
+func (k ObjectKey) String() string {
+	if k.Namespace == "" {
+		return fmt.Sprintf("%s %s", k.GroupVersionKind, k.Name)
+	}
+	return fmt.Sprintf("%s %s/%s", k.GroupVersionKind, k.Namespace, k.Name)
+}
+
+// BatchResult holds the results of CalculateBatch. Every item has either a result or an error.
+type BatchResult struct {
+	Results map[ObjectKey]*PatchResult
+	Errors  map[ObjectKey]error
+}
+
+// Err combines the errors of the items, nil if there are none.
+func (r *BatchResult) Err() error {
+	errs := make([]error, 0, len(r.Errors))
+	for key, err := range r.Errors {
+		errs = append(errs, errors.WrapWithDetails(err, "Failed to calculate patch", "object", key.String()))
+	}
+	return errors.Combine(errs...)
+}
+
+// CalculateBatch calculates the patches of the items on at most the given number of goroutines.
+// The items are keyed by the group, version, kind, namespace and name of their modified object.
+// Items whose key can not be determined, or is taken by an earlier item, are not calculated,
+// their error is keyed by their index in the batch, like "#3". A Maker implementing ContextMaker gets the context,
+// the items not started when the context is done get the error of the context.
+//
+// PatchMaker and the patchers of this package are safe for concurrent use,
+// but an object must not be shared between items.
+func CalculateBatch(ctx context.Context, maker Maker, items []BatchItem, workers int) *BatchResult {
+	result := &BatchResult{
+		Results: map[ObjectKey]*PatchResult{},
+		Errors:  map[ObjectKey]error{},
+	}
+
+	// Keys are assigned before any work is scheduled, so that every key gets a single result or error.
+	keys := make([]ObjectKey, len(items))
+	scheduled := make([]int, 0, len(items))
+	seen := make(map[ObjectKey]bool, len(items))
+	for i, item := range items {
+		key, err := batchObjectKey(item.Modified)
+		if err != nil {
+			key, err = batchObjectKey(item.Current)
+		}
+		if err == nil && seen[key] {
+			err = errors.NewWithDetails("duplicate object in batch", "object", key.String())
+		}
+		if err != nil {
+			result.Errors[ObjectKey{Name: fmt.Sprintf("#%d", i)}] = err
+			continue
+		}
+		seen[key] = true
+		keys[i] = key
+		scheduled = append(scheduled, i)
+	}
+
+	indexes := make(chan int)
+	var mu sync.Mutex
+
+	if workers < 1 {
+		workers = 1
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				patchResult, err := calculateBatchItem(ctx, maker, items[i])
+
+				mu.Lock()
+				if err != nil {
+					result.Errors[keys[i]] = err
+				} else {
+					result.Results[keys[i]] = patchResult
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, i := range scheduled {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return result
+}
+
+func calculateBatchItem(ctx context.Context, maker Maker, item BatchItem) (*PatchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if contextMaker, ok := maker.(ContextMaker); ok {
+		return contextMaker.CalculateContext(ctx, item.Current, item.Modified, item.Options...)
+	}
+	return maker.Calculate(item.Current, item.Modified, item.Options...)
+}
+
+func batchObjectKey(obj runtime.Object) (ObjectKey, error) {
+	if obj == nil {
+		return ObjectKey{}, errors.New("object is nil")
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return ObjectKey{}, err
+	}
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	if gvk.Kind == "" {
+		gvk.Kind = objectKind(obj)
+	}
+	return ObjectKey{GroupVersionKind: gvk, Namespace: accessor.GetNamespace(), Name: accessor.GetName()}, nil
+}
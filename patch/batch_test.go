@@ -0,0 +1,117 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"emperror.dev/errors"
+)
+
+// TestCalculateBatch is meant to be run with -race as well, it shares the patch maker,
+// the annotator, the patchers and the schema registry between the workers.
+func TestCalculateBatch(t *testing.T) {
+	registry := NewSchemaRegistry()
+	if err := registry.AddCustomResourceDefinition([]byte(testCRD)); err != nil {
+		t.Fatal(err)
+	}
+	patchMaker := NewPatchMaker(DefaultAnnotator, &K8sStrategicMergePatcher{}, &BaseJSONMergePatcher{},
+		WithSchemaRegistry(registry), WithMissingOriginalPolicy(MissingOriginalFail))
+
+	var items []BatchItem
+	for i := 0; i < 50; i++ {
+		current := newTestPod(testContainer{Name: "app", Image: "app:1"})
+		current.Name = fmt.Sprintf("pod-%d", i)
+		if err := DefaultAnnotator.SetLastAppliedAnnotation(current); err != nil {
+			t.Fatal(err)
+		}
+		modified := newTestPod(testContainer{Name: "app", Image: fmt.Sprintf("app:%d", i%2+1)})
+		modified.Name = current.Name
+		items = append(items, BatchItem{Current: current, Modified: modified})
+
+		widget := newTestWidget(map[string]interface{}{"name": "a", "size": int64(1)})
+		widget.SetName(current.Name)
+		if err := DefaultAnnotator.SetLastAppliedAnnotation(widget); err != nil {
+			t.Fatal(err)
+		}
+		items = append(items, BatchItem{Current: widget, Modified: widget.DeepCopy()})
+	}
+	unknown := newTestPod(testContainer{Name: "app", Image: "app:1"})
+	unknown.Name = "unknown"
+	items = append(items, BatchItem{Current: unknown, Modified: unknown.DeepCopyObject()})
+
+	result := CalculateBatch(context.Background(), patchMaker, items, 8)
+
+	if len(result.Results) != 100 || len(result.Errors) != 1 {
+		t.Fatalf("Expected 100 results and 1 error, got %d results and errors %v", len(result.Results), result.Errors)
+	}
+	var missingOriginalError *MissingOriginalError
+	if !errors.As(result.Errors[ObjectKey{GroupVersionKind: unknown.GroupVersionKind(), Namespace: "default", Name: "unknown"}], &missingOriginalError) {
+		t.Fatalf("Expected a MissingOriginalError, got %v", result.Errors)
+	}
+	if result.Err() == nil {
+		t.Fatal("Expected combined error")
+	}
+	for key, patchResult := range result.Results {
+		wantEmpty := key.Kind == "Widget" || key.Name[len(key.Name)-1]%2 == 0
+		if patchResult.IsEmpty() != wantEmpty {
+			t.Fatalf("Unexpected patch for %s: %s", key, patchResult.Patch)
+		}
+	}
+}
+
+func TestCalculateBatchDuplicatesAndCancellation(t *testing.T) {
+	current := newTestPod(testContainer{Name: "app", Image: "app:1"})
+	key := ObjectKey{GroupVersionKind: current.GroupVersionKind(), Namespace: "default", Name: "test"}
+	failing := func(current, modified []byte) ([]byte, []byte, error) {
+		return nil, nil, errors.New("option failed")
+	}
+	items := []BatchItem{
+		{Current: current, Modified: current.DeepCopyObject()},
+		{Current: current.DeepCopyObject(), Modified: current.DeepCopyObject(), Options: []CalculateOption{failing}},
+	}
+
+	// The first item keeps its key, the duplicate is rejected under its index, whichever of them fails.
+	result := CalculateBatch(context.Background(), DefaultPatchMaker, items, 2)
+	if len(result.Results) != 1 || result.Results[key] == nil || len(result.Errors) != 1 || result.Errors[ObjectKey{Name: "#1"}] == nil {
+		t.Fatalf("Expected a result for the first item and an error for the duplicate, got %v, %v", result.Results, result.Errors)
+	}
+
+	items[0], items[1] = items[1], items[0]
+	result = CalculateBatch(context.Background(), DefaultPatchMaker, items, 2)
+	if len(result.Results) != 0 || len(result.Errors) != 2 {
+		t.Fatalf("Expected errors for both items, got %v, %v", result.Results, result.Errors)
+	}
+	if err := result.Errors[key]; err == nil || err.Error() != "Failed to apply option function: option failed" {
+		t.Fatalf("Expected the error of the first item, got %v", err)
+	}
+	if err := result.Errors[ObjectKey{Name: "#1"}]; err == nil || err.Error() != "duplicate object in batch" {
+		t.Fatalf("Expected a duplicate error for the second item, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result = CalculateBatch(ctx, DefaultPatchMaker, items[:1], 1)
+	for _, err := range result.Errors {
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected context.Canceled, got %v", err)
+		}
+	}
+	if len(result.Errors) != 1 {
+		t.Fatalf("Expected an error for the cancelled item, got %v", result.Errors)
+	}
+}
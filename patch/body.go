@@ -0,0 +1,150 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"emperror.dev/errors"
+	json "github.com/json-iterator/go"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// PatchType returns the type of Patch to send to the API server: a strategic merge patch for typed objects,
+// a JSON merge patch for unstructured objects, including the ones with a registered schema, as custom resources
+// do not accept strategic merge patches.
+func (p *PatchResult) PatchType() types.PatchType {
+	return p.patchType
+}
+
+// patchType returns the type of the patches calculated for the object.
+func patchType(obj runtime.Object) types.PatchType {
+	if _, ok := obj.(*unstructured.Unstructured); ok {
+		return types.MergePatchType
+	}
+	return types.StrategicMergePatchType
+}
+
+type PatchBodyOption func(*patchBodyOptions)
+
+type patchBodyOptions struct {
+	resourceVersion bool
+	uid             bool
+	annotator       *Annotator
+	modified        runtime.Object
+}
+
+// WithResourceVersionPrecondition adds the resourceVersion of the current object to the patch body,
+// so that the API server rejects the patch with a conflict if the object has been changed since.
+func WithResourceVersionPrecondition() PatchBodyOption {
+	return func(o *patchBodyOptions) {
+		o.resourceVersion = true
+	}
+}
+
+// WithUIDPrecondition adds the uid of the current object to the patch body,
+// so that the patch is rejected if the object has been deleted and created again since.
+func WithUIDPrecondition() PatchBodyOption {
+	return func(o *patchBodyOptions) {
+		o.uid = true
+	}
+}
+
+// WithLastAppliedAnnotation adds the annotations and labels the annotator sets on the modified object
+// to the patch body, so that a single patch both updates the object and records its last applied configuration.
+// The modified object is not changed. The history of the configurations is not carried over.
+func WithLastAppliedAnnotation(annotator *Annotator, modified runtime.Object) PatchBodyOption {
+	return func(o *patchBodyOptions) {
+		o.annotator = annotator
+		o.modified = modified
+	}
+}
+
+// PatchBody returns Patch with the metadata requested by the options added, ready to be sent with PatchType.
+// Preconditions are read from Current, so they are not available if the metadata has been ignored.
+func (p *PatchResult) PatchBody(opts ...PatchBodyOption) ([]byte, error) {
+	options := &patchBodyOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	var body map[string]interface{}
+	if err := unmarshalWithNumbers(p.Patch, &body); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal patch")
+	}
+	if body == nil {
+		body = map[string]interface{}{}
+	}
+
+	if options.resourceVersion || options.uid {
+		var current map[string]interface{}
+		if err := unmarshalWithNumbers(p.Current, &current); err != nil {
+			return nil, errors.Wrap(err, "could not unmarshal current object")
+		}
+		for field, enabled := range map[string]bool{"resourceVersion": options.resourceVersion, "uid": options.uid} {
+			if !enabled {
+				continue
+			}
+			value, ok, err := unstructured.NestedString(current, "metadata", field)
+			if err != nil || !ok || value == "" {
+				return nil, errors.Errorf("current object has no %s to use as a precondition", field)
+			}
+			if err := unstructured.SetNestedField(body, value, "metadata", field); err != nil {
+				return nil, errors.WrapWithDetails(err, "could not set precondition", "field", field)
+			}
+		}
+	}
+
+	if options.annotator != nil {
+		if err := addLastAppliedAnnotation(body, options.annotator, options.modified); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.ConfigCompatibleWithStandardLibrary.Marshal(body)
+}
+
+// addLastAppliedAnnotation adds the annotations and labels the annotator would set or change on the modified object to the body.
+func addLastAppliedAnnotation(body map[string]interface{}, annotator *Annotator, modified runtime.Object) error {
+	annotated := modified.DeepCopyObject()
+	if err := annotator.SetLastAppliedAnnotation(annotated); err != nil {
+		return errors.Wrap(err, "could not set last applied annotation")
+	}
+
+	before, err := meta.Accessor(modified)
+	if err != nil {
+		return errors.Wrap(err, "could not access metadata of modified object")
+	}
+	after, err := meta.Accessor(annotated)
+	if err != nil {
+		return errors.Wrap(err, "could not access metadata of annotated object")
+	}
+
+	for field, values := range map[string][2]map[string]string{
+		"annotations": {before.GetAnnotations(), after.GetAnnotations()},
+		"labels":      {before.GetLabels(), after.GetLabels()},
+	} {
+		for key, value := range values[1] {
+			if previous, ok := values[0][key]; ok && previous == value {
+				continue
+			}
+			if err := unstructured.SetNestedField(body, value, "metadata", field, key); err != nil {
+				return errors.WrapWithDetails(err, "could not add metadata to patch", "field", field, "key", key)
+			}
+		}
+	}
+	return nil
+}
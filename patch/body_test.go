@@ -0,0 +1,117 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"testing"
+
+	json "github.com/json-iterator/go"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestPatchType(t *testing.T) {
+	registry := NewSchemaRegistry()
+	if err := registry.AddCustomResourceDefinition([]byte(testCRD)); err != nil {
+		t.Fatal(err)
+	}
+	patchMaker := NewPatchMaker(DefaultAnnotator, &K8sStrategicMergePatcher{}, &BaseJSONMergePatcher{}, WithSchemaRegistry(registry))
+
+	pod := newTestPod(testContainer{Name: "app", Image: "app:1"})
+	unstructuredPod := &unstructured.Unstructured{Object: mustToUnstructured(mustMarshal(pod))}
+	widget := newTestWidget(map[string]interface{}{"name": "a"})
+
+	tests := []struct {
+		name   string
+		maker  Maker
+		object runtime.Object
+		want   types.PatchType
+	}{
+		{name: "typed object", maker: patchMaker, object: pod, want: types.StrategicMergePatchType},
+		{name: "unstructured object", maker: patchMaker, object: unstructuredPod, want: types.MergePatchType},
+		{name: "unstructured object with schema", maker: patchMaker, object: widget, want: types.MergePatchType},
+		{name: "server-side apply", maker: newServerSideApplyTestMaker(t), object: pod, want: types.MergePatchType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.maker.Calculate(tt.object, tt.object)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := result.PatchType(); got != tt.want {
+				t.Fatalf("Expected patch type %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPatchBody(t *testing.T) {
+	current := newTestPod(testContainer{Name: "app", Image: "app:1"})
+	if err := DefaultAnnotator.SetLastAppliedAnnotation(current); err != nil {
+		t.Fatal(err)
+	}
+	current.SetResourceVersion("42")
+	current.SetUID("c0ffee")
+	modified := newTestPod(testContainer{Name: "app", Image: "app:2"})
+
+	result, err := DefaultPatchMaker.Calculate(current, modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := result.PatchBody(WithResourceVersionPrecondition(), WithUIDPrecondition(), WithLastAppliedAnnotation(DefaultAnnotator, modified))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(modified.GetAnnotations()) != 0 {
+		t.Fatal("Expected the modified object to be left unchanged")
+	}
+
+	patched, err := (&K8sStrategicMergePatcher{}).StrategicMergePatch(mustMarshal(current), body, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	patchedPod := &testPod{}
+	if err := json.Unmarshal(patched, patchedPod); err != nil {
+		t.Fatal(err)
+	}
+	if patchedPod.GetResourceVersion() != "42" || patchedPod.GetUID() != "c0ffee" {
+		t.Fatalf("Expected preconditions in the patch body, got %s", body)
+	}
+	if patchedPod.Spec.Containers[0].Image != "app:2" {
+		t.Fatalf("Expected the patch in the patch body, got %s", body)
+	}
+
+	original, err := DefaultAnnotator.GetOriginalConfiguration(patchedPod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := DefaultAnnotator.SetLastAppliedAnnotation(modified); err != nil {
+		t.Fatal(err)
+	}
+	want, err := DefaultAnnotator.GetOriginalConfiguration(modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(original) != string(want) {
+		t.Fatalf("Expected last applied configuration %s, got %s", want, original)
+	}
+
+	result.Current = mustMarshal(newTestPod())
+	if _, err := result.PatchBody(WithResourceVersionPrecondition()); err == nil {
+		t.Fatal("Expected an error without a resourceVersion")
+	}
+}
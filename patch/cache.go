@@ -0,0 +1,258 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"emperror.dev/errors"
+	json "github.com/json-iterator/go"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	defaultCacheMaxEntries = 1024
+	defaultCacheTTL        = 10 * time.Minute
+)
+
+// CachingMaker is a Maker that remembers the results of another Maker, so the patch of an object
+// that has not changed on either side since the last calculation is not calculated again.
+//
+// Results are keyed by the UID and the resourceVersion of the current object, the hash of the
+// normalized modified object, and a fingerprint of the options. Options are functions,
+// so their fingerprint is the hash of the current object after they are applied.
+// Objects without a UID or a resourceVersion, like the ones not created yet, are not cached.
+//
+// The key is computed on every call, hit or miss, by marshalling both objects, applying the options
+// and normalizing the modified object, which the other Maker repeats on a miss. So the cache saves
+// the comparison of the objects, not their serialization.
+//
+// Every caller gets its own copy of a cached result, except for the Trace and the values of the Changes,
+// which are shared.
+type CachingMaker struct {
+	maker      Maker
+	maxEntries int
+	ttl        time.Duration
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	stats   CacheStats
+}
+
+type CachingMakerOption func(*CachingMaker)
+
+// CacheStats are the counters of a CachingMaker.
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Entries   int
+}
+
+type cacheEntry struct {
+	key     string
+	result  *PatchResult
+	expires time.Time
+}
+
+var _ ContextMaker = &CachingMaker{}
+
+func NewCachingMaker(maker Maker, opts ...CachingMakerOption) *CachingMaker {
+	m := &CachingMaker{
+		maker:      maker,
+		maxEntries: defaultCacheMaxEntries,
+		ttl:        defaultCacheTTL,
+		now:        time.Now,
+		entries:    map[string]*list.Element{},
+		lru:        list.New(),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// WithMaxEntries bounds the number of cached results, the least recently used ones are evicted first.
+func WithMaxEntries(maxEntries int) CachingMakerOption {
+	return func(m *CachingMaker) {
+		m.maxEntries = maxEntries
+	}
+}
+
+// WithTTL sets how long a result is kept in the cache, ten minutes by default.
+func WithTTL(ttl time.Duration) CachingMakerOption {
+	return func(m *CachingMaker) {
+		m.ttl = ttl
+	}
+}
+
+func (m *CachingMaker) Calculate(currentObject, modifiedObject runtime.Object, opts ...CalculateOption) (*PatchResult, error) {
+	return m.CalculateContext(context.Background(), currentObject, modifiedObject, opts...)
+}
+
+func (m *CachingMaker) CalculateContext(ctx context.Context, currentObject, modifiedObject runtime.Object, opts ...CalculateOption) (*PatchResult, error) {
+	key, err := cacheKey(currentObject, modifiedObject, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to calculate cache key")
+	}
+	if key == "" {
+		return m.calculate(ctx, currentObject, modifiedObject, opts)
+	}
+
+	if result, ok := m.get(key); ok {
+		return result.copy(), nil
+	}
+
+	result, err := m.calculate(ctx, currentObject, modifiedObject, opts)
+	if err != nil {
+		return nil, err
+	}
+	m.add(key, result.copy())
+	return result, nil
+}
+
+// Stats returns the counters of the cache.
+func (m *CachingMaker) Stats() CacheStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := m.stats
+	stats.Entries = m.lru.Len()
+	return stats
+}
+
+func (m *CachingMaker) calculate(ctx context.Context, currentObject, modifiedObject runtime.Object, opts []CalculateOption) (*PatchResult, error) {
+	if contextMaker, ok := m.maker.(ContextMaker); ok {
+		return contextMaker.CalculateContext(ctx, currentObject, modifiedObject, opts...)
+	}
+	return m.maker.Calculate(currentObject, modifiedObject, opts...)
+}
+
+func (m *CachingMaker) get(key string) (*PatchResult, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	element, ok := m.entries[key]
+	if !ok {
+		m.stats.Misses++
+		return nil, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if m.now().After(entry.expires) {
+		m.remove(element)
+		m.stats.Misses++
+		return nil, false
+	}
+	m.lru.MoveToFront(element)
+	m.stats.Hits++
+	return entry.result, true
+}
+
+func (m *CachingMaker) add(key string, result *PatchResult) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if element, ok := m.entries[key]; ok {
+		m.remove(element)
+	}
+	m.entries[key] = m.lru.PushFront(&cacheEntry{key: key, result: result, expires: m.now().Add(m.ttl)})
+	for m.lru.Len() > m.maxEntries {
+		m.remove(m.lru.Back())
+		m.stats.Evictions++
+	}
+}
+
+func (m *CachingMaker) remove(element *list.Element) {
+	m.lru.Remove(element)
+	delete(m.entries, element.Value.(*cacheEntry).key)
+}
+
+// cacheKey returns the key of the result, or an empty string if it can not be cached.
+func cacheKey(currentObject, modifiedObject runtime.Object, opts []CalculateOption) (string, error) {
+	accessor, err := meta.Accessor(currentObject)
+	if err != nil {
+		return "", err
+	}
+	if accessor.GetUID() == "" || accessor.GetResourceVersion() == "" {
+		return "", nil
+	}
+
+	current, err := json.ConfigCompatibleWithStandardLibrary.Marshal(currentObject)
+	if err != nil {
+		return "", err
+	}
+	modified, err := json.ConfigCompatibleWithStandardLibrary.Marshal(modifiedObject)
+	if err != nil {
+		return "", err
+	}
+	for _, opt := range opts {
+		current, modified, err = opt(current, modified)
+		if err != nil {
+			return "", err
+		}
+	}
+	modified, err = deleteNullInJsonBytes(modified)
+	if err != nil {
+		return "", err
+	}
+
+	optionsFingerprint := ""
+	if len(opts) > 0 {
+		optionsFingerprint = sha256Hex(current)
+	}
+	return string(accessor.GetUID()) + "/" + accessor.GetResourceVersion() + "/" + sha256Hex(modified) + "/" + optionsFingerprint, nil
+}
+
+// copy returns a shallow copy of the result with its own slices, so the caller can modify them.
+func (p *PatchResult) copy() *PatchResult {
+	c := *p
+	c.Patch = copyBytes(p.Patch)
+	c.Current = copyBytes(p.Current)
+	c.Modified = copyBytes(p.Modified)
+	c.Original = copyBytes(p.Original)
+	c.patched = copyBytes(p.patched)
+	if p.Changes != nil {
+		c.Changes = append([]Change{}, p.Changes...)
+	}
+	if p.Conflicts != nil {
+		c.Conflicts = append([]FieldConflict{}, p.Conflicts...)
+	}
+	if p.ImmutableFieldChanges != nil {
+		c.ImmutableFieldChanges = append([]Change{}, p.ImmutableFieldChanges...)
+	}
+	if p.UnrecoverablePaths != nil {
+		c.UnrecoverablePaths = append([]string{}, p.UnrecoverablePaths...)
+	}
+	return &c
+}
+
+func copyBytes(data []byte) []byte {
+	if data == nil {
+		return nil
+	}
+	return append([]byte{}, data...)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,114 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestCachingMaker(t *testing.T) {
+	now := time.Now()
+	maker := NewCachingMaker(DefaultPatchMaker, WithMaxEntries(2), WithTTL(time.Minute))
+	maker.now = func() time.Time { return now }
+
+	current := newTestPod(testContainer{Name: "app", Image: "app:1"})
+	if err := DefaultAnnotator.SetLastAppliedAnnotation(current); err != nil {
+		t.Fatal(err)
+	}
+	current.UID = types.UID("uid")
+	current.ResourceVersion = "1"
+	modified := newTestPod(testContainer{Name: "app", Image: "app:2"})
+
+	calculate := func(opts ...CalculateOption) *PatchResult {
+		t.Helper()
+		result, err := maker.Calculate(current, modified, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	expectStats := func(want CacheStats) {
+		t.Helper()
+		if stats := maker.Stats(); stats != want {
+			t.Fatalf("Expected stats %+v, got %+v", want, stats)
+		}
+	}
+
+	first := calculate()
+	if string(calculate().Patch) != string(first.Patch) {
+		t.Fatal("Expected the cached result")
+	}
+	expectStats(CacheStats{Hits: 1, Misses: 1, Entries: 1})
+
+	// Different options are cached separately.
+	if result := calculate(IgnoreField("spec")); !result.IsEmpty() {
+		t.Fatalf("Expected an empty patch with spec ignored, got %s", result.Patch)
+	}
+	expectStats(CacheStats{Hits: 1, Misses: 2, Entries: 2})
+
+	// A new resource version of current is a new entry, evicting the least recently used one.
+	current.ResourceVersion = "2"
+	calculate()
+	expectStats(CacheStats{Hits: 1, Misses: 3, Evictions: 1, Entries: 2})
+
+	now = now.Add(2 * time.Minute)
+	calculate()
+	expectStats(CacheStats{Hits: 1, Misses: 4, Evictions: 1, Entries: 2})
+
+	// Objects not created yet are not cached.
+	current.UID = ""
+	calculate()
+	expectStats(CacheStats{Hits: 1, Misses: 4, Evictions: 1, Entries: 2})
+}
+
+func TestCachingMakerReturnsCopies(t *testing.T) {
+	maker := NewCachingMaker(changesPatchMaker)
+
+	current := newTestPod(testContainer{Name: "app", Image: "app:1"})
+	if err := DefaultAnnotator.SetLastAppliedAnnotation(current); err != nil {
+		t.Fatal(err)
+	}
+	current.UID = types.UID("uid")
+	current.ResourceVersion = "1"
+	modified := newTestPod(testContainer{Name: "app", Image: "app:2"})
+
+	first, err := maker.Calculate(current, modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	patch := string(first.Patch)
+	changes := len(first.Changes)
+	first.Patch[0] = 'x'
+	first.Changes[0].Path = "modified"
+	first.Changes = append(first.Changes, Change{Path: "appended"})
+
+	for i := 0; i < 2; i++ {
+		result, err := maker.Calculate(current, modified)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(result.Patch) != patch || len(result.Changes) != changes || result.Changes[0].Path == "modified" {
+			t.Fatalf("Expected the cached result not to be affected by the changes of other callers, got %s %v", result.Patch, result.Changes)
+		}
+		result.Patch[0] = 'y'
+		result.Changes[0].Path = "modified"
+	}
+	if stats := maker.Stats(); stats.Hits != 2 {
+		t.Fatalf("Expected cache hits, got %+v", stats)
+	}
+}
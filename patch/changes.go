@@ -0,0 +1,353 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"emperror.dev/errors"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+type ChangeOperation string
+
+const (
+	ChangeAdd     ChangeOperation = "add"
+	ChangeRemove  ChangeOperation = "remove"
+	ChangeReplace ChangeOperation = "replace"
+)
+
+// ChangeCause tells why a field is changed by a patch, based on the three-way comparison
+// of the original, the current and the modified object.
+type ChangeCause string
+
+const (
+	// ChangeCauseLocal means the modified object differs from the original, the field was changed locally.
+	ChangeCauseLocal ChangeCause = "local"
+	// ChangeCauseDrift means the current object differs from the original, the field was changed remotely.
+	ChangeCauseDrift ChangeCause = "drift"
+	// ChangeCauseConflict means the field was changed both locally and remotely.
+	ChangeCauseConflict ChangeCause = "conflict"
+)
+
+// Change is a single field level change made by a patch.
+type Change struct {
+	// Path of the changed field, like "spec.template.spec.containers[name=app].image".
+	// List elements are identified by their merge key if there is one, otherwise by their index.
+	Path      string
+	Operation ChangeOperation
+	// Old is the current value of the field, nil if the field is added.
+	// Numbers are json.Number values, to keep integers above 2^53 precise.
+	Old interface{}
+	// New is the value of the field after the patch is applied, nil if the field is removed.
+	New interface{}
+	// Cause tells whether the change reverts a remote drift or applies a local change.
+	Cause ChangeCause
+}
+
+// missing stands for a field that is not present in one of the compared objects.
+type missing struct{}
+
+// changeSource holds the values of the original and the modified object at the location being compared.
+type changeSource struct {
+	original interface{}
+	modified interface{}
+}
+
+func (s changeSource) field(key string) changeSource {
+	return changeSource{original: fieldOf(s.original, key), modified: fieldOf(s.modified, key)}
+}
+
+func (s changeSource) element(mergeKey string, keyValue interface{}, index int) changeSource {
+	return changeSource{original: elementOf(s.original, mergeKey, keyValue, index), modified: elementOf(s.modified, mergeKey, keyValue, index)}
+}
+
+// cause classifies the change of a field from its current value.
+func (s changeSource) cause(current interface{}) ChangeCause {
+	local := !reflect.DeepEqual(s.original, s.modified)
+	remote := !reflect.DeepEqual(s.original, current)
+	switch {
+	case local && remote:
+		return ChangeCauseConflict
+	case remote:
+		return ChangeCauseDrift
+	default:
+		return ChangeCauseLocal
+	}
+}
+
+func fieldOf(value interface{}, key string) interface{} {
+	if m, ok := value.(map[string]interface{}); ok {
+		if v, ok := m[key]; ok {
+			return v
+		}
+	}
+	return missing{}
+}
+
+func elementOf(value interface{}, mergeKey string, keyValue interface{}, index int) interface{} {
+	list, ok := value.([]interface{})
+	if !ok {
+		return missing{}
+	}
+	if mergeKey == "" {
+		if index >= 0 && index < len(list) {
+			return list[index]
+		}
+		return missing{}
+	}
+	for _, item := range list {
+		if m, ok := item.(map[string]interface{}); ok && reflect.DeepEqual(m[mergeKey], keyValue) {
+			return m
+		}
+	}
+	return missing{}
+}
+
+// redactedValues holds the redacted values of the current and the patched object at the location being compared,
+// which become the Old and New values of the changes, so that the changes do not leak sensitive values.
+type redactedValues struct {
+	old interface{}
+	new interface{}
+}
+
+func (r redactedValues) field(key string) redactedValues {
+	return redactedValues{old: fieldOf(r.old, key), new: fieldOf(r.new, key)}
+}
+
+func (r redactedValues) element(oldIndex, newIndex int) redactedValues {
+	return redactedValues{old: elementOf(r.old, "", nil, oldIndex), new: elementOf(r.new, "", nil, newIndex)}
+}
+
+func (c Change) String() string {
+	switch c.Operation {
+	case ChangeAdd:
+		return fmt.Sprintf("%s %s: %v", c.Operation, c.Path, c.New)
+	case ChangeRemove:
+		return fmt.Sprintf("%s %s: %v", c.Operation, c.Path, c.Old)
+	default:
+		return fmt.Sprintf("%s %s: %v -> %v", c.Operation, c.Path, c.Old, c.New)
+	}
+}
+
+// calculateChanges lists the changes between the current and the patched object.
+// Comparing the objects instead of decoding the patch takes care of strategic merge
+// patch directives like $setElementOrder and $patch. Merge keys of lists are looked up
+// in the patch metadata, which can be nil. The original and the modified object are
+// used to tell the cause of the changes, original can be nil. The values of the changes are redacted
+// with the rules of the redactor for the kind of the object.
+func calculateChanges(current, patched, original, modified []byte, lookupPatchMeta strategicpatch.LookupPatchMeta, redactor *Redactor, kind string) ([]Change, error) {
+	var currentMap, patchedMap map[string]interface{}
+	if err := unmarshalWithNumbers(current, &currentMap); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal current object")
+	}
+	if err := unmarshalWithNumbers(patched, &patchedMap); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal patched object")
+	}
+
+	redactedCurrent, redactedPatched, err := redactor.redactPair(current, patched, kind)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not redact sensitive values")
+	}
+	var redacted redactedValues
+	if err := unmarshalWithNumbers(redactedCurrent, &redacted.old); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal redacted current object")
+	}
+	if err := unmarshalWithNumbers(redactedPatched, &redacted.new); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal redacted patched object")
+	}
+
+	source := changeSource{original: map[string]interface{}{}, modified: map[string]interface{}{}}
+	if len(original) > 0 {
+		if err := unmarshalWithNumbers(original, &source.original); err != nil {
+			return nil, errors.Wrap(err, "could not unmarshal original object")
+		}
+	}
+	if err := unmarshalWithNumbers(modified, &source.modified); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal modified object")
+	}
+
+	var changes []Change
+	diffMaps("", currentMap, patchedMap, source, redacted, lookupPatchMeta, &changes)
+	return changes, nil
+}
+
+func diffMaps(path string, old, new map[string]interface{}, source changeSource, redacted redactedValues, lookupPatchMeta strategicpatch.LookupPatchMeta, changes *[]Change) {
+	keys := make([]string, 0, len(old)+len(new))
+	for key := range old {
+		keys = append(keys, key)
+	}
+	for key := range new {
+		if _, ok := old[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		oldValue, inOld := old[key]
+		newValue, inNew := new[key]
+		fieldPath := joinChangePath(path, key)
+		fieldSource := source.field(key)
+		fieldRedacted := redacted.field(key)
+		switch {
+		case !inNew:
+			*changes = append(*changes, Change{Path: fieldPath, Operation: ChangeRemove, Old: fieldRedacted.old, Cause: fieldSource.cause(oldValue)})
+		case !inOld:
+			*changes = append(*changes, Change{Path: fieldPath, Operation: ChangeAdd, New: fieldRedacted.new, Cause: fieldSource.cause(missing{})})
+		default:
+			diffValues(fieldPath, key, oldValue, newValue, fieldSource, fieldRedacted, lookupPatchMeta, changes)
+		}
+	}
+}
+
+func diffValues(path, key string, old, new interface{}, source changeSource, redacted redactedValues, lookupPatchMeta strategicpatch.LookupPatchMeta, changes *[]Change) {
+	if reflect.DeepEqual(old, new) {
+		return
+	}
+
+	switch oldValue := old.(type) {
+	case map[string]interface{}:
+		if newValue, ok := new.(map[string]interface{}); ok {
+			diffMaps(path, oldValue, newValue, source, redacted, lookupStructPatchMeta(lookupPatchMeta, key), changes)
+			return
+		}
+	case []interface{}:
+		if newValue, ok := new.([]interface{}); ok && isListOfMaps(oldValue) && isListOfMaps(newValue) {
+			elementPatchMeta, mergeKey := lookupSlicePatchMeta(lookupPatchMeta, key)
+			diffLists(path, mergeKey, oldValue, newValue, source, redacted, elementPatchMeta, changes)
+			return
+		}
+	}
+
+	*changes = append(*changes, Change{Path: path, Operation: ChangeReplace, Old: redacted.old, New: redacted.new, Cause: source.cause(old)})
+}
+
+func diffLists(path, mergeKey string, old, new []interface{}, source changeSource, redacted redactedValues, lookupPatchMeta strategicpatch.LookupPatchMeta, changes *[]Change) {
+	if mergeKey == "" || !hasMergeKey(old, mergeKey) || !hasMergeKey(new, mergeKey) {
+		for i := 0; i < len(old) || i < len(new); i++ {
+			elementPath := fmt.Sprintf("%s[%d]", path, i)
+			elementSource := source.element("", nil, i)
+			elementRedacted := redacted.element(i, i)
+			switch {
+			case i >= len(new):
+				*changes = append(*changes, Change{Path: elementPath, Operation: ChangeRemove, Old: elementRedacted.old, Cause: elementSource.cause(old[i])})
+			case i >= len(old):
+				*changes = append(*changes, Change{Path: elementPath, Operation: ChangeAdd, New: elementRedacted.new, Cause: elementSource.cause(missing{})})
+			default:
+				diffMaps(elementPath, old[i].(map[string]interface{}), new[i].(map[string]interface{}), elementSource, elementRedacted, lookupPatchMeta, changes)
+			}
+		}
+		return
+	}
+
+	matched := make(map[int]bool, len(new))
+	for i, item := range old {
+		oldElement := item.(map[string]interface{})
+		elementPath := fmt.Sprintf("%s[%s=%v]", path, mergeKey, oldElement[mergeKey])
+		elementSource := source.element(mergeKey, oldElement[mergeKey], 0)
+		found := false
+		for j, newItem := range new {
+			newElement := newItem.(map[string]interface{})
+			if !matched[j] && reflect.DeepEqual(oldElement[mergeKey], newElement[mergeKey]) {
+				matched[j] = true
+				found = true
+				diffMaps(elementPath, oldElement, newElement, elementSource, redacted.element(i, j), lookupPatchMeta, changes)
+				break
+			}
+		}
+		if !found {
+			*changes = append(*changes, Change{Path: elementPath, Operation: ChangeRemove, Old: redacted.element(i, -1).old, Cause: elementSource.cause(oldElement)})
+		}
+	}
+	for j, newItem := range new {
+		if matched[j] {
+			continue
+		}
+		newElement := newItem.(map[string]interface{})
+		elementPath := fmt.Sprintf("%s[%s=%v]", path, mergeKey, newElement[mergeKey])
+		elementSource := source.element(mergeKey, newElement[mergeKey], 0)
+		*changes = append(*changes, Change{Path: elementPath, Operation: ChangeAdd, New: redacted.element(-1, j).new, Cause: elementSource.cause(missing{})})
+	}
+}
+
+func joinChangePath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func isListOfMaps(list []interface{}) bool {
+	for _, item := range list {
+		if _, ok := item.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func hasMergeKey(list []interface{}, mergeKey string) bool {
+	for _, item := range list {
+		if _, ok := item.(map[string]interface{})[mergeKey]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func lookupStructPatchMeta(lookupPatchMeta strategicpatch.LookupPatchMeta, key string) strategicpatch.LookupPatchMeta {
+	if lookupPatchMeta == nil {
+		return nil
+	}
+	subPatchMeta, _, err := lookupPatchMeta.LookupPatchMetadataForStruct(key)
+	if err != nil {
+		return nil
+	}
+	return subPatchMeta
+}
+
+func lookupSlicePatchMeta(lookupPatchMeta strategicpatch.LookupPatchMeta, key string) (strategicpatch.LookupPatchMeta, string) {
+	if lookupPatchMeta == nil {
+		return nil, ""
+	}
+	elementPatchMeta, patchMeta, err := lookupPatchMeta.LookupPatchMetadataForSlice(key)
+	if err != nil {
+		return nil, ""
+	}
+	return elementPatchMeta, patchMeta.GetPatchMergeKey()
+}
+
+// WithChanges makes the patch maker list the field level changes of the patch in PatchResult.Changes,
+// and the changes to immutable fields in PatchResult.ImmutableFieldChanges. Listing the changes applies
+// the patch and compares the objects field by field, which is skipped by default.
+func WithChanges() PatchMakerOption {
+	return func(p *PatchMaker) {
+		p.changes = true
+	}
+}
+
+// ChangesByCause returns the changes of the patch with the given cause.
+func (p *PatchResult) ChangesByCause(cause ChangeCause) []Change {
+	var changes []Change
+	for _, change := range p.Changes {
+		if change.Cause == cause {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
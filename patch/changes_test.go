@@ -0,0 +1,125 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var changesPatchMaker = NewPatchMaker(DefaultAnnotator, &K8sStrategicMergePatcher{}, &BaseJSONMergePatcher{}, WithChanges())
+
+func TestChangesWithMergeKeys(t *testing.T) {
+	current := newTestPod(
+		testContainer{Name: "app", Image: "app:1"},
+		testContainer{Name: "sidecar", Image: "sidecar:1"},
+	)
+	if err := DefaultAnnotator.SetLastAppliedAnnotation(current); err != nil {
+		t.Fatal(err)
+	}
+	modified := newTestPod(
+		testContainer{Name: "app", Image: "app:2", Args: []string{"--debug"}},
+		testContainer{Name: "init", Image: "init:1"},
+	)
+
+	result, err := changesPatchMaker.Calculate(current, modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Change{
+		{Path: "spec.containers[name=app].args", Operation: ChangeAdd, New: []interface{}{"--debug"}, Cause: ChangeCauseLocal},
+		{Path: "spec.containers[name=app].image", Operation: ChangeReplace, Old: "app:1", New: "app:2", Cause: ChangeCauseLocal},
+		{Path: "spec.containers[name=sidecar]", Operation: ChangeRemove, Old: map[string]interface{}{"name": "sidecar", "image": "sidecar:1"}, Cause: ChangeCauseLocal},
+		{Path: "spec.containers[name=init]", Operation: ChangeAdd, New: map[string]interface{}{"name": "init", "image": "init:1"}, Cause: ChangeCauseLocal},
+	}
+	if !reflect.DeepEqual(result.Changes, want) {
+		t.Fatalf("Expected changes %v, got %v", want, result.Changes)
+	}
+
+	// Without WithChanges the patch is not applied until the patched object is needed.
+	result, err = DefaultPatchMaker.Calculate(current, modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Changes != nil || result.patched != nil {
+		t.Fatalf("Expected no changes to be listed, got %v", result.Changes)
+	}
+	if operations, err := result.JSONPatch(); err != nil || len(operations) == 0 {
+		t.Fatalf("Expected JSON Patch operations, got %v, %v", operations, err)
+	}
+}
+
+func TestChangesWithoutMergeKeys(t *testing.T) {
+	newObject := func(items ...interface{}) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "example.com/v1",
+			"kind":       "Example",
+			"metadata":   map[string]interface{}{"name": "test"},
+			"spec":       map[string]interface{}{"items": items},
+		}}
+	}
+	current := newObject(map[string]interface{}{"name": "a", "value": "1"})
+	if err := DefaultAnnotator.SetLastAppliedAnnotation(current); err != nil {
+		t.Fatal(err)
+	}
+	modified := newObject(map[string]interface{}{"name": "a", "value": "2"}, map[string]interface{}{"name": "b"})
+
+	result, err := changesPatchMaker.Calculate(current, modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Change{
+		{Path: "spec.items[0].value", Operation: ChangeReplace, Old: "1", New: "2", Cause: ChangeCauseLocal},
+		{Path: "spec.items[1]", Operation: ChangeAdd, New: map[string]interface{}{"name": "b"}, Cause: ChangeCauseLocal},
+	}
+	if !reflect.DeepEqual(result.Changes, want) {
+		t.Fatalf("Expected changes %v, got %v", want, result.Changes)
+	}
+}
+
+func TestChangeCauses(t *testing.T) {
+	current := newTestPod(testContainer{Name: "app", Image: "app:1", Args: []string{"--original"}})
+	current.Spec.Hostname = "original"
+	if err := DefaultAnnotator.SetLastAppliedAnnotation(current); err != nil {
+		t.Fatal(err)
+	}
+	current.Spec.Hostname = "remote"
+	current.Spec.Containers[0].Args = []string{"--remote"}
+
+	modified := newTestPod(testContainer{Name: "app", Image: "app:2", Args: []string{"--local"}})
+	modified.Spec.Hostname = "original"
+
+	result, err := changesPatchMaker.Calculate(current, modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]ChangeCause{
+		"spec.containers[name=app].args":  ChangeCauseConflict,
+		"spec.containers[name=app].image": ChangeCauseLocal,
+		"spec.hostname":                   ChangeCauseDrift,
+	}
+	got := map[string]ChangeCause{}
+	for _, change := range result.Changes {
+		got[change.Path] = change.Cause
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Expected change causes %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"emperror.dev/errors"
+	json "github.com/json-iterator/go"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ConfigHashLabel is the default label key used by WithConfigHashLabel.
+const ConfigHashLabel = "banzaicloud.com/last-applied-hash"
+
+// WithConfigHashLabel makes SetLastAppliedAnnotation write the ConfigHash of the applied
+// configuration as a label on the object as well, to be able to select out-of-date objects.
+func WithConfigHashLabel(key string) AnnotatorOption {
+	return func(a *Annotator) {
+		a.hashLabel = key
+	}
+}
+
+// ConfigHash returns a short hash of the configuration after removing null values
+// the same way as DeleteNullInJson does. The hash does not depend on the order of object keys.
+func ConfigHash(configuration []byte) (string, error) {
+	// DeleteNullInJson marshals the configuration with sorted map keys.
+	normalized, err := deleteNullInJsonBytes(configuration)
+	if err != nil {
+		return "", errors.Wrap(err, "could not normalize configuration")
+	}
+	sum := sha256.Sum256(normalized)
+	// 80 bits are plenty to tell configurations apart and keep the label short.
+	return hex.EncodeToString(sum[:10]), nil
+}
+
+// GetConfigHash returns the ConfigHash of the configuration SetLastAppliedAnnotation would record for the object.
+// Unlike GetModifiedConfiguration, it leaves the object intact.
+func (a *Annotator) GetConfigHash(obj runtime.Object) (string, error) {
+	modified, err := json.ConfigCompatibleWithStandardLibrary.Marshal(obj)
+	if err != nil {
+		return "", errors.Wrap(err, "could not marshal object")
+	}
+	return a.configHash(modified)
+}
+
+// configHash returns the ConfigHash of the marshaled object without the annotations and the label written
+// by the annotator, which are removed from a decoded copy the same way GetModifiedConfiguration removes them.
+func (a *Annotator) configHash(modified []byte) (string, error) {
+	var obj map[string]interface{}
+	if err := unmarshalWithNumbers(modified, &obj); err != nil {
+		return "", errors.Wrap(err, "could not unmarshal configuration")
+	}
+	if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+		if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+			delete(annotations, a.key)
+			delete(annotations, a.historyKey())
+			if len(annotations) == 0 {
+				delete(metadata, "annotations")
+			}
+		}
+		if labels, ok := metadata["labels"].(map[string]interface{}); ok && a.hashLabel != "" {
+			if _, ok := labels[a.hashLabel]; ok {
+				delete(labels, a.hashLabel)
+				if len(labels) == 0 {
+					delete(metadata, "labels")
+				}
+			}
+		}
+	}
+	configuration, err := json.ConfigCompatibleWithStandardLibrary.Marshal(obj)
+	if err != nil {
+		return "", errors.Wrap(err, "could not marshal configuration")
+	}
+	return ConfigHash(configuration)
+}
+
+func (a *Annotator) setConfigHashLabel(obj runtime.Object, modified []byte) error {
+	if a.hashLabel == "" {
+		return nil
+	}
+
+	hash, err := ConfigHash(modified)
+	if err != nil {
+		return err
+	}
+
+	labels, err := a.metadataAccessor.Labels(obj)
+	if err != nil {
+		return err
+	}
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[a.hashLabel] = hash
+	return a.metadataAccessor.SetLabels(obj, labels)
+}
+
+// IsUpToDate reports whether the config hash label of the current object matches the hash of the
+// modified object, that is the modified object is the same as the one applied last time.
+// Changes made to the current object since then are not taken into account.
+// It is always false if the annotator does not write a config hash label.
+func (a *Annotator) IsUpToDate(currentObject, modifiedObject runtime.Object) (bool, error) {
+	modified, err := json.ConfigCompatibleWithStandardLibrary.Marshal(modifiedObject)
+	if err != nil {
+		return false, errors.Wrap(err, "could not marshal modified object")
+	}
+	return a.isUpToDate(currentObject, modified)
+}
+
+// isUpToDate is IsUpToDate with the modified object already marshaled.
+func (a *Annotator) isUpToDate(currentObject runtime.Object, modified []byte) (bool, error) {
+	if a.hashLabel == "" {
+		return false, nil
+	}
+
+	labels, err := a.metadataAccessor.Labels(currentObject)
+	if err != nil {
+		return false, err
+	}
+	currentHash, ok := labels[a.hashLabel]
+	if !ok {
+		return false, nil
+	}
+
+	modifiedHash, err := a.configHash(modified)
+	if err != nil {
+		return false, err
+	}
+	return currentHash == modifiedHash, nil
+}
+
+// WithConfigHashFastPath makes Calculate, CalculateBytes and CalculateMap return an empty patch without running the three-way merge,
+// if the annotator reports that the modified object is up to date, see Annotator.IsUpToDate.
+// The objects of such a result are not processed by the options. Changes made to the current object by others
+// since the last apply are not detected in this case.
+func WithConfigHashFastPath() PatchMakerOption {
+	return func(p *PatchMaker) {
+		p.configHashFastPath = true
+	}
+}
+
+// IsUpToDate is a cheap check whether the modified object is the same as the one applied last time,
+// based on the config hash label written by the annotator. See Annotator.IsUpToDate.
+func (p *PatchMaker) IsUpToDate(currentObject, modifiedObject runtime.Object) (bool, error) {
+	if p.annotator == nil {
+		return false, nil
+	}
+	return p.annotator.IsUpToDate(currentObject, modifiedObject)
+}
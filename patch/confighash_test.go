@@ -0,0 +1,137 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestConfigHashIsStable(t *testing.T) {
+	a, err := ConfigHash([]byte(`{"a":1,"b":{"c":"d","e":"f"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ConfigHash([]byte(`{"b":{"e":"f","c":"d","g":null},"a":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Fatalf("Expected equal hashes, got %s and %s", a, b)
+	}
+	c, err := ConfigHash([]byte(`{"a":2,"b":{"c":"d","e":"f"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == c {
+		t.Fatalf("Expected different hashes for different configurations, got %s", a)
+	}
+}
+
+func TestConfigHashFastPath(t *testing.T) {
+	annotator := NewAnnotator(LastAppliedConfig, WithConfigHashLabel(ConfigHashLabel))
+	patchMaker := NewPatchMaker(annotator, &K8sStrategicMergePatcher{}, &BaseJSONMergePatcher{}, WithConfigHashFastPath())
+
+	newObject := func() *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata": map[string]interface{}{
+				"name":   "test",
+				"labels": map[string]interface{}{"app": "test"},
+			},
+			"data": map[string]interface{}{"key": "value"},
+		}}
+	}
+
+	current := newObject()
+	if err := annotator.SetLastAppliedAnnotation(current); err != nil {
+		t.Fatal(err)
+	}
+	hash := current.GetLabels()[ConfigHashLabel]
+	if len(hash) != 20 {
+		t.Fatalf("Expected config hash label, got labels %v", current.GetLabels())
+	}
+	original, err := annotator.GetOriginalConfiguration(current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := ConfigHash(original); want != hash {
+		t.Fatalf("Expected the label to be the hash of the last applied configuration %s, got %s", want, hash)
+	}
+
+	// The fast path ignores remote changes.
+	unstructured.SetNestedField(current.Object, "changed", "data", "key")
+	unchanged := newObject()
+	result, err := patchMaker.Calculate(current, unchanged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.IsEmpty() {
+		t.Fatalf("Expected the fast path to return an empty patch, got %s", result.Patch)
+	}
+	if !reflect.DeepEqual(unchanged, newObject()) {
+		t.Fatalf("Expected the modified object to be left intact, got %v", unchanged.Object)
+	}
+	if string(result.Current) != string(mustMarshal(current)) || len(result.Modified) == 0 || string(result.Original) != string(original) {
+		t.Fatalf("Expected the fast path to return the objects, got %s", result)
+	}
+	if result.PatchType() != types.MergePatchType || result.kind != "ConfigMap" {
+		t.Fatalf("Expected the patch type and kind of the object, got %q and %q", result.PatchType(), result.kind)
+	}
+	current.SetResourceVersion("42")
+	result, err = patchMaker.Calculate(current, newObject())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if operations, err := result.JSONPatch(WithResourceVersionTest()); err != nil || len(operations) != 1 {
+		t.Fatalf("Expected a resourceVersion test, got %v, %v", operations, err)
+	}
+
+	modified := newObject()
+	unstructured.SetNestedField(modified.Object, "new", "data", "key")
+	result, err = patchMaker.Calculate(current, modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result.Patch) != `{"data":{"key":"new"}}` {
+		t.Fatalf("Expected patch for the changed configuration, got %s", result.Patch)
+	}
+	if modified.GetLabels()[ConfigHashLabel] != "" || current.GetLabels()[ConfigHashLabel] != hash {
+		t.Fatalf("Expected labels to be left intact, got %v and %v", modified.GetLabels(), current.GetLabels())
+	}
+}
+
+func TestConfigHashOfTypedObject(t *testing.T) {
+	annotator := NewAnnotator(LastAppliedConfig, WithConfigHashLabel(ConfigHashLabel))
+	current := newTestPod(testContainer{Name: "app", Image: "app:1", Port: 8080})
+	if err := annotator.SetLastAppliedAnnotation(current); err != nil {
+		t.Fatal(err)
+	}
+	modified := newTestPod(testContainer{Name: "app", Image: "app:1", Port: 8080})
+	if upToDate, err := annotator.IsUpToDate(current, modified); err != nil || !upToDate {
+		t.Fatalf("Expected the typed object to be up to date, got %v, %v", upToDate, err)
+	}
+	if modified.Annotations != nil || modified.Labels != nil {
+		t.Fatalf("Expected the modified object to be left intact, got %v and %v", modified.Annotations, modified.Labels)
+	}
+	modified.Spec.Containers[0].Port = 8081
+	if upToDate, err := annotator.IsUpToDate(current, modified); err != nil || upToDate {
+		t.Fatalf("Expected the changed object to be out of date, got %v, %v", upToDate, err)
+	}
+}
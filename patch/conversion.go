@@ -0,0 +1,138 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"fmt"
+
+	"emperror.dev/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// WithScheme makes the patch maker convert unstructured objects of kinds registered in the scheme
+// to their Go types, so they get the same strategic merge patch as the typed objects would,
+// instead of a JSON merge patch. Objects of other kinds are left unstructured.
+func WithScheme(scheme *runtime.Scheme) PatchMakerOption {
+	return func(p *PatchMaker) {
+		p.scheme = scheme
+	}
+}
+
+// toTyped converts the unstructured current and modified objects to their Go types, if their kinds are registered
+// in the scheme. Both objects are left unstructured if either has fields the Go type does not know,
+// like fields of a newer API version or typos, as the conversion would drop them from the patch.
+func (p *PatchMaker) toTyped(currentObject, modifiedObject runtime.Object) (runtime.Object, runtime.Object, error) {
+	typedCurrent, currentComplete, err := p.convertToTyped(currentObject)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to convert current object to its Go type")
+	}
+	typedModified, modifiedComplete, err := p.convertToTyped(modifiedObject)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to convert modified object to its Go type")
+	}
+	if !currentComplete || !modifiedComplete {
+		return currentObject, modifiedObject, nil
+	}
+	return typedCurrent, typedModified, nil
+}
+
+// convertToTyped converts an unstructured object to its Go type, if the kind of the object is registered in the scheme.
+// It reports whether every field of the object made it into the typed object.
+func (p *PatchMaker) convertToTyped(obj runtime.Object) (runtime.Object, bool, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok || p.scheme == nil {
+		return obj, true, nil
+	}
+	gvk := u.GroupVersionKind()
+	if !p.scheme.Recognizes(gvk) {
+		return obj, true, nil
+	}
+
+	typed, err := p.scheme.New(gvk)
+	if err != nil {
+		return nil, false, errors.WrapWithDetails(err, "could not create typed object", "gvk", gvk)
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, typed); err != nil {
+		return nil, false, errors.WrapWithDetails(err, "could not convert unstructured object", "gvk", gvk)
+	}
+	roundTripped, err := runtime.DefaultUnstructuredConverter.ToUnstructured(typed)
+	if err != nil {
+		return nil, false, errors.WrapWithDetails(err, "could not convert typed object", "gvk", gvk)
+	}
+	var lost []string
+	lostFields("", u.Object, roundTripped, &lost)
+	if len(lost) > 0 {
+		return obj, false, nil
+	}
+	return typed, true, nil
+}
+
+// lostFields collects the paths of the non-empty fields of the input that are missing from the output.
+// Empty and zero values are left out of typed objects with omitempty, so those are not considered lost.
+func lostFields(path string, input, output map[string]interface{}, lost *[]string) {
+	for key, value := range input {
+		if isEmptyValue(value) {
+			continue
+		}
+		fieldPath := joinChangePath(path, key)
+		outputValue, ok := output[key]
+		if !ok {
+			*lost = append(*lost, fieldPath)
+			continue
+		}
+		switch inputValue := value.(type) {
+		case map[string]interface{}:
+			if outputMap, ok := outputValue.(map[string]interface{}); ok {
+				lostFields(fieldPath, inputValue, outputMap, lost)
+			}
+		case []interface{}:
+			outputList, ok := outputValue.([]interface{})
+			if !ok || len(outputList) != len(inputValue) {
+				*lost = append(*lost, fieldPath)
+				continue
+			}
+			for i, item := range inputValue {
+				inputElement, ok := item.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if outputElement, ok := outputList[i].(map[string]interface{}); ok {
+					lostFields(fmt.Sprintf("%s[%d]", fieldPath, i), inputElement, outputElement, lost)
+				}
+			}
+		}
+	}
+}
+
+func isEmptyValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	case string:
+		return v == ""
+	case bool:
+		return !v
+	case int64:
+		return v == 0
+	case float64:
+		return v == 0
+	}
+	return false
+}
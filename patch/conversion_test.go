@@ -0,0 +1,120 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestWithScheme(t *testing.T) {
+	scheme := runtime.NewScheme()
+	scheme.AddKnownTypeWithName(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, &testPod{})
+	patchMaker := NewPatchMaker(DefaultAnnotator, &K8sStrategicMergePatcher{}, &BaseJSONMergePatcher{}, WithScheme(scheme))
+
+	current := newTestPod(
+		testContainer{Name: "app", Image: "app:1"},
+		testContainer{Name: "sidecar", Image: "sidecar:1"},
+	)
+	if err := DefaultAnnotator.SetLastAppliedAnnotation(current); err != nil {
+		t.Fatal(err)
+	}
+	current.Spec.Containers[0].Args = []string{"--defaulted"}
+	modified := newTestPod(
+		testContainer{Name: "app", Image: "app:2"},
+		testContainer{Name: "sidecar", Image: "sidecar:1"},
+	)
+
+	typedResult, err := DefaultPatchMaker.Calculate(current, modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unstructuredCurrent := &unstructured.Unstructured{Object: mustToUnstructured(mustMarshal(current))}
+	unstructuredModified := &unstructured.Unstructured{Object: mustToUnstructured(mustMarshal(modified))}
+
+	result, err := patchMaker.Calculate(unstructuredCurrent, unstructuredModified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result.Patch) != string(typedResult.Patch) || !reflect.DeepEqual(result.Changes, typedResult.Changes) {
+		t.Fatalf("Expected the result of typed objects %s, got %s", typedResult.Patch, result.Patch)
+	}
+
+	// Without the scheme, the list of containers is replaced by a JSON merge patch.
+	result, err = DefaultPatchMaker.Calculate(unstructuredCurrent, unstructuredModified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result.Patch) == string(typedResult.Patch) {
+		t.Fatalf("Expected a JSON merge patch, got %s", result.Patch)
+	}
+}
+
+func TestWithSchemeKeepsUnknownFields(t *testing.T) {
+	scheme := runtime.NewScheme()
+	scheme.AddKnownTypeWithName(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, &testPod{})
+	patchMaker := NewPatchMaker(DefaultAnnotator, &K8sStrategicMergePatcher{}, &BaseJSONMergePatcher{}, WithScheme(scheme))
+
+	current := newTestPod(testContainer{Name: "app", Image: "app:1"})
+	if err := DefaultAnnotator.SetLastAppliedAnnotation(current); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		field  string
+		modify func(obj map[string]interface{})
+	}{
+		{
+			name:  "field of a newer version",
+			field: "newField",
+			modify: func(obj map[string]interface{}) {
+				obj["spec"].(map[string]interface{})["newField"] = "value"
+			},
+		},
+		{
+			name:  "misspelled field of a list element",
+			field: "imagee",
+			modify: func(obj map[string]interface{}) {
+				container := obj["spec"].(map[string]interface{})["containers"].([]interface{})[0]
+				container.(map[string]interface{})["imagee"] = "app:2"
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modified := &unstructured.Unstructured{Object: mustToUnstructured(mustMarshal(current))}
+			tt.modify(modified.Object)
+
+			result, err := patchMaker.Calculate(&unstructured.Unstructured{Object: mustToUnstructured(mustMarshal(current))}, modified)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(result.Patch), tt.field) {
+				t.Fatalf("Expected the unknown field in the patch, got %s", result.Patch)
+			}
+			if result.PatchType() != types.MergePatchType {
+				t.Fatalf("Expected a JSON merge patch, got %s", result.PatchType())
+			}
+		})
+	}
+}
@@ -15,6 +15,7 @@
 package patch
 
 import (
+	stdjson "encoding/json"
 	"reflect"
 	"unsafe"
 
@@ -101,25 +102,42 @@ func init() {
 	)
 }
 
+// DeleteNullInJson removes null and zero values from a JSON document. The returned map is decoded
+// like encoding/json does, with numbers as float64, the returned document keeps integers intact.
 func DeleteNullInJson(jsonBytes []byte) ([]byte, map[string]interface{}, error) {
+	o, err := deleteNullInJsonBytes(jsonBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var filteredMap map[string]interface{}
+	if err := json.ConfigCompatibleWithStandardLibrary.Unmarshal(o, &filteredMap); err != nil {
+		return nil, nil, errors.Wrap(err, "could not unmarshal filtered patch map")
+	}
+
+	return o, filteredMap, nil
+}
+
+// deleteNullInJsonBytes is DeleteNullInJson for callers that only need the document.
+func deleteNullInJsonBytes(jsonBytes []byte) ([]byte, error) {
 	var patchMap map[string]interface{}
 
-	err := json.Unmarshal(jsonBytes, &patchMap)
+	err := unmarshalWithNumbers(jsonBytes, &patchMap)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "could not unmarshal json patch")
+		return nil, errors.Wrap(err, "could not unmarshal json patch")
 	}
 
 	filteredMap, err := deleteNullInObj(patchMap)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "could not delete null values from patch map")
+		return nil, errors.Wrap(err, "could not delete null values from patch map")
 	}
 
 	o, err := json.ConfigCompatibleWithStandardLibrary.Marshal(filteredMap)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "could not marshal filtered patch map")
+		return nil, errors.Wrap(err, "could not marshal filtered patch map")
 	}
 
-	return o, filteredMap, err
+	return o, nil
 }
 
 func deleteNullInObj(m map[string]interface{}) (map[string]interface{}, error) {
@@ -139,7 +157,7 @@ func deleteNullInObj(m map[string]interface{}) (map[string]interface{}, error) {
 				return nil, errors.Errorf("could not delete null values from subslice")
 			}
 			filteredMap[key] = slice
-		case string, float64, bool, int64, nil:
+		case string, float64, bool, int64, stdjson.Number, nil:
 			filteredMap[key] = val
 		case map[string]interface{}:
 			if len(typedVal) == 0 {
@@ -176,7 +194,7 @@ func deleteNullInSlice(m []interface{}) ([]interface{}, error) {
 				return nil, errors.Errorf("could not delete null values from subslice")
 			}
 			filteredSlice[key] = filteredSubSlice
-		case string, float64, bool, int64, nil:
+		case string, float64, bool, int64, stdjson.Number, nil:
 			filteredSlice[key] = val
 		case map[string]interface{}:
 			filteredMap, err := deleteNullInObj(typedVal)
@@ -191,7 +209,7 @@ func deleteNullInSlice(m []interface{}) ([]interface{}, error) {
 
 func deleteDataField(obj []byte, fieldName string) ([]byte, error) {
 	var objectMap map[string]interface{}
-	err := json.Unmarshal(obj, &objectMap)
+	err := unmarshalWithNumbers(obj, &objectMap)
 	if err != nil {
 		return []byte{}, errors.Wrap(err, "could not unmarshal byte sequence")
 	}
@@ -206,7 +224,7 @@ func deleteDataField(obj []byte, fieldName string) ([]byte, error) {
 
 func deleteStatusField(obj []byte) ([]byte, error) {
 	var objectMap map[string]interface{}
-	err := json.Unmarshal(obj, &objectMap)
+	err := unmarshalWithNumbers(obj, &objectMap)
 	if err != nil {
 		return []byte{}, errors.Wrap(err, "could not unmarshal byte sequence")
 	}
@@ -221,7 +239,7 @@ func deleteStatusField(obj []byte) ([]byte, error) {
 
 func deleteVolumeClaimTemplateFields(obj []byte) ([]byte, error) {
 	resource := map[string]interface{}{}
-	err := json.Unmarshal(obj, &resource)
+	err := unmarshalWithNumbers(obj, &resource)
 	if err != nil {
 		return []byte{}, errors.Wrap(err, "could not unmarshal byte sequence")
 	}
@@ -0,0 +1,288 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"fmt"
+	"strings"
+
+	"emperror.dev/errors"
+	json "github.com/json-iterator/go"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	colorReset = "\x1b[0m"
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+	colorCyan  = "\x1b[36m"
+
+	defaultContextLines = 3
+
+	// Above this many line comparisons the differing part of the documents
+	// is shown as removed and added as a whole instead of looking for common lines.
+	maxDiffComparisons = 4000000
+)
+
+type DiffOption func(*diffOptions)
+
+type diffOptions struct {
+	color         bool
+	contextLines  int
+	ignoredFields []string
+}
+
+// WithColor highlights the diff with ANSI escape sequences.
+func WithColor() DiffOption {
+	return func(o *diffOptions) {
+		o.color = true
+	}
+}
+
+// WithContextLines sets the number of unchanged lines shown around the changes, three by default.
+func WithContextLines(lines int) DiffOption {
+	return func(o *diffOptions) {
+		o.contextLines = lines
+	}
+}
+
+// WithIgnoredFields leaves the given fields out of the diff.
+// Paths are dot separated, "[]" selects every element of a list, e.g. "metadata.managedFields".
+func WithIgnoredFields(paths ...string) DiffOption {
+	return func(o *diffOptions) {
+		o.ignoredFields = append(o.ignoredFields, paths...)
+	}
+}
+
+// Diff renders a unified diff between the YAML representation of the current object
+// and the current object with the patch applied. The output is deterministic,
+// it is empty if the patch is empty. Sensitive values are redacted, see WithRedactor.
+func (p *PatchResult) Diff(opts ...DiffOption) (string, error) {
+	options := &diffOptions{
+		contextLines: defaultContextLines,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	if p.IsEmpty() {
+		return "", nil
+	}
+	patchedData, err := p.getPatched()
+	if err != nil {
+		return "", errors.Wrap(err, "could not apply patch")
+	}
+	if patchedData == nil {
+		return "", errors.New("patch result has no patched object to compare with")
+	}
+
+	ignored := make([]fieldPath, 0, len(options.ignoredFields))
+	for _, path := range options.ignoredFields {
+		parsed, err := parseFieldPath(path)
+		if err != nil {
+			return "", err
+		}
+		ignored = append(ignored, parsed)
+	}
+
+	redactedCurrent, redactedPatched, err := p.getRedactor().redactPair(p.Current, patchedData, p.kind)
+	if err != nil {
+		return "", errors.Wrap(err, "could not redact sensitive values")
+	}
+
+	current, err := toDiffYAML(redactedCurrent, ignored)
+	if err != nil {
+		return "", errors.Wrap(err, "could not convert current object to YAML")
+	}
+	patched, err := toDiffYAML(redactedPatched, ignored)
+	if err != nil {
+		return "", errors.Wrap(err, "could not convert patched object to YAML")
+	}
+
+	return unifiedDiff(current, patched, options.contextLines, options.color), nil
+}
+
+func toDiffYAML(data []byte, ignored []fieldPath) ([]string, error) {
+	if len(ignored) > 0 {
+		var obj map[string]interface{}
+		if err := unmarshalWithNumbers(data, &obj); err != nil {
+			return nil, err
+		}
+		for _, p := range ignored {
+			p.delete(obj)
+		}
+		var err error
+		data, err = json.ConfigCompatibleWithStandardLibrary.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Keys are sorted by the YAML encoder, which makes the output deterministic.
+	y, err := yaml.JSONToYAML(data)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimSuffix(string(y), "\n"), "\n"), nil
+}
+
+type diffLine struct {
+	kind byte
+	text string
+}
+
+// diffLines returns the edit script that turns a into b, based on the longest common subsequence of lines.
+func diffLines(a, b []string) []diffLine {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]diffLine, 0, len(a)+len(b))
+	for _, text := range a[:prefix] {
+		lines = append(lines, diffLine{kind: ' ', text: text})
+	}
+
+	x, y := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(x)*len(y) > maxDiffComparisons {
+		for _, text := range x {
+			lines = append(lines, diffLine{kind: '-', text: text})
+		}
+		for _, text := range y {
+			lines = append(lines, diffLine{kind: '+', text: text})
+		}
+	} else {
+		// lcs[i][j] is the length of the longest common subsequence of x[i:] and y[j:].
+		lcs := make([][]int, len(x)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(y)+1)
+		}
+		for i := len(x) - 1; i >= 0; i-- {
+			for j := len(y) - 1; j >= 0; j-- {
+				if x[i] == y[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else if lcs[i+1][j] >= lcs[i][j+1] {
+					lcs[i][j] = lcs[i+1][j]
+				} else {
+					lcs[i][j] = lcs[i][j+1]
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < len(x) || j < len(y) {
+			switch {
+			case i < len(x) && j < len(y) && x[i] == y[j]:
+				lines = append(lines, diffLine{kind: ' ', text: x[i]})
+				i++
+				j++
+			case j == len(y) || (i < len(x) && lcs[i+1][j] >= lcs[i][j+1]):
+				lines = append(lines, diffLine{kind: '-', text: x[i]})
+				i++
+			default:
+				lines = append(lines, diffLine{kind: '+', text: y[j]})
+				j++
+			}
+		}
+	}
+
+	for _, text := range a[len(a)-suffix:] {
+		lines = append(lines, diffLine{kind: ' ', text: text})
+	}
+	return lines
+}
+
+func unifiedDiff(a, b []string, contextLines int, color bool) string {
+	if contextLines < 0 {
+		contextLines = 0
+	}
+	lines := diffLines(a, b)
+
+	// Line numbers in a and b before each line of the edit script.
+	aLine := make([]int, len(lines)+1)
+	bLine := make([]int, len(lines)+1)
+	for k, line := range lines {
+		aLine[k+1], bLine[k+1] = aLine[k], bLine[k]
+		if line.kind != '+' {
+			aLine[k+1]++
+		}
+		if line.kind != '-' {
+			bLine[k+1]++
+		}
+	}
+
+	var sb strings.Builder
+	writeLine := func(c, text string) {
+		if color && c != "" {
+			sb.WriteString(c + text + colorReset + "\n")
+		} else {
+			sb.WriteString(text + "\n")
+		}
+	}
+	writeLine("", "--- current")
+	writeLine("", "+++ patched")
+
+	for k := 0; k < len(lines); {
+		if lines[k].kind == ' ' {
+			k++
+			continue
+		}
+		start := k - contextLines
+		if start < 0 {
+			start = 0
+		}
+		last := k
+		for j := k + 1; j < len(lines); j++ {
+			if lines[j].kind == ' ' {
+				continue
+			}
+			if j-last-1 > 2*contextLines {
+				break
+			}
+			last = j
+		}
+		end := last + contextLines + 1
+		if end > len(lines) {
+			end = len(lines)
+		}
+
+		writeLine(colorCyan, fmt.Sprintf("@@ -%s +%s @@",
+			hunkRange(aLine[start], aLine[end]-aLine[start]),
+			hunkRange(bLine[start], bLine[end]-bLine[start])))
+		for _, line := range lines[start:end] {
+			switch line.kind {
+			case '-':
+				writeLine(colorRed, "-"+line.text)
+			case '+':
+				writeLine(colorGreen, "+"+line.text)
+			default:
+				writeLine("", " "+line.text)
+			}
+		}
+		k = end
+	}
+
+	return sb.String()
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
@@ -0,0 +1,69 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	current := newTestPod(
+		testContainer{Name: "app", Image: "app:1", Args: []string{"--a", "--b", "--c", "--d", "--e", "--f", "--g", "--h"}},
+	)
+	current.Labels = map[string]string{"app": "test"}
+	modified := newTestPod(
+		testContainer{Name: "app", Image: "app:2", Args: []string{"--a", "--b", "--c", "--d", "--e", "--f", "--g", "--i"}},
+	)
+	modified.Labels = map[string]string{"app": "changed"}
+
+	result, err := DefaultPatchMaker.Calculate(current, modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := result.Diff(WithContextLines(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"--- current",
+		"+++ patched",
+		"@@ -4,3 +4,3 @@",
+		"   labels:",
+		"-    app: test",
+		"+    app: changed",
+		"   name: test",
+		"@@ -17,4 +17,4 @@",
+		"     - --g",
+		"-    - --h",
+		"-    image: app:1",
+		"+    - --i",
+		"+    image: app:2",
+		"     name: app",
+		"",
+	}, "\n")
+	if diff != want {
+		t.Fatalf("Diff() =\n%s\nwant\n%s", diff, want)
+	}
+
+	diff, err = result.Diff(WithIgnoredFields("spec"), WithColor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(diff, "image") || !strings.Contains(diff, colorGreen+"+    app: changed"+colorReset) {
+		t.Fatalf("Diff() with ignored fields and color =\n%s", diff)
+	}
+}
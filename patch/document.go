@@ -0,0 +1,149 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+
+	"emperror.dev/errors"
+	json "github.com/json-iterator/go"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"sigs.k8s.io/yaml"
+)
+
+// TypeHint tells how objects given as documents are compared, see CalculateBytes.
+// Without a hint, the group, version and kind of the current document are used.
+type TypeHint struct {
+	// Object is an instance of the Go type of the objects, to compare them with a strategic merge patch.
+	Object runtime.Object
+	// GroupVersionKind of the objects, looked up in the scheme and the schema registry
+	// of the patch maker if Object is not set, see WithScheme and WithSchemaRegistry.
+	GroupVersionKind schema.GroupVersionKind
+}
+
+// CalculateBytes is like Calculate, but takes the objects as JSON or YAML documents.
+// The documents are compared as they are, without converting them to objects first.
+func (p *PatchMaker) CalculateBytes(current, modified []byte, hint TypeHint, opts ...CalculateOption) (*PatchResult, error) {
+	return p.CalculateBytesContext(context.Background(), current, modified, hint, opts...)
+}
+
+// CalculateBytesContext is like CalculateBytes, but gives up as soon as the context is done, see CalculateContext.
+func (p *PatchMaker) CalculateBytesContext(ctx context.Context, current, modified []byte, hint TypeHint, opts ...CalculateOption) (*PatchResult, error) {
+	current, err := toJSONDocument(current)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to convert current document to JSON")
+	}
+	modified, err = toJSONDocument(modified)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to convert modified document to JSON")
+	}
+
+	// The map is converted to an object, which only works with the int64 and float64 numbers of unstructured objects.
+	// The documents themselves are compared, so integers above 2^53 are kept intact nevertheless.
+	var currentMap map[string]interface{}
+	if err := utiljson.Unmarshal(current, &currentMap); err != nil {
+		return nil, errors.Wrap(err, "Failed to unmarshal current document")
+	}
+
+	return p.calculateDocuments(ctx, currentMap, current, modified, hint, opts...)
+}
+
+// CalculateMap is like CalculateBytes, but takes the objects as unstructured maps.
+func (p *PatchMaker) CalculateMap(current, modified map[string]interface{}, hint TypeHint, opts ...CalculateOption) (*PatchResult, error) {
+	return p.CalculateMapContext(context.Background(), current, modified, hint, opts...)
+}
+
+// CalculateMapContext is like CalculateMap, but gives up as soon as the context is done, see CalculateContext.
+func (p *PatchMaker) CalculateMapContext(ctx context.Context, current, modified map[string]interface{}, hint TypeHint, opts ...CalculateOption) (*PatchResult, error) {
+	currentJSON, err := json.ConfigCompatibleWithStandardLibrary.Marshal(current)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to convert current object to byte sequence")
+	}
+	modifiedJSON, err := json.ConfigCompatibleWithStandardLibrary.Marshal(modified)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to convert modified object to byte sequence")
+	}
+
+	return p.calculateDocuments(ctx, current, currentJSON, modifiedJSON, hint, opts...)
+}
+
+// calculateDocuments takes the same route as CalculateContext, including the config hash fast path,
+// but compares the documents as they are instead of marshalling the objects.
+func (p *PatchMaker) calculateDocuments(ctx context.Context, currentMap map[string]interface{}, current, modified []byte, hint TypeHint, opts ...CalculateOption) (*PatchResult, error) {
+	currentObject, err := p.documentObject(currentMap, hint)
+	if err != nil {
+		return nil, err
+	}
+
+	if result, err := p.fastPathResult(currentObject, current, modified); result != nil || err != nil {
+		return result, err
+	}
+
+	return p.calculate(ctx, currentObject, current, modified, opts...)
+}
+
+// documentObject returns the object the current document stands for in the calculation,
+// which decides the kind of patch and holds the original configuration.
+func (p *PatchMaker) documentObject(current map[string]interface{}, hint TypeHint) (runtime.Object, error) {
+	prototype := hint.Object
+	if prototype == nil {
+		u := &unstructured.Unstructured{Object: current}
+		gvk := hint.GroupVersionKind
+		if gvk.Empty() {
+			gvk = u.GroupVersionKind()
+		}
+		if p.scheme == nil || !p.scheme.Recognizes(gvk) {
+			if gvk != u.GroupVersionKind() {
+				// The group, version and kind are needed to look up the schema, without changing the document of the caller.
+				u = &unstructured.Unstructured{Object: make(map[string]interface{}, len(current))}
+				for key, value := range current {
+					u.Object[key] = value
+				}
+				u.SetGroupVersionKind(gvk)
+			}
+			return u, nil
+		}
+		var err error
+		prototype, err = p.scheme.New(gvk)
+		if err != nil {
+			return nil, errors.WrapWithDetails(err, "could not create typed object", "gvk", gvk)
+		}
+	}
+
+	t := reflect.TypeOf(prototype)
+	if t.Kind() != reflect.Ptr {
+		return nil, errors.Errorf("type hint %s is not a pointer", t)
+	}
+	typed, ok := reflect.New(t.Elem()).Interface().(runtime.Object)
+	if !ok {
+		return nil, errors.Errorf("type hint %s is not a runtime.Object", t)
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(current, typed); err != nil {
+		return nil, errors.WrapWithDetails(err, "could not convert current document", "type", t.String())
+	}
+	return typed, nil
+}
+
+func toJSONDocument(data []byte) ([]byte, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return data, nil
+	}
+	return yaml.YAMLToJSON(data)
+}
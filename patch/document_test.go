@@ -0,0 +1,138 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"context"
+	"testing"
+
+	"emperror.dev/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+func TestCalculateBytes(t *testing.T) {
+	current := newTestPod(
+		testContainer{Name: "app", Image: "app:1", Port: 8080},
+		testContainer{Name: "sidecar", Image: "sidecar:1", Port: 9090},
+	)
+	if err := DefaultAnnotator.SetLastAppliedAnnotation(current); err != nil {
+		t.Fatal(err)
+	}
+	current.Spec.Containers[0].Args = []string{"--defaulted"}
+	modified := newTestPod(
+		testContainer{Name: "app", Image: "app:2", Port: 8081},
+		testContainer{Name: "sidecar", Image: "sidecar:1", Port: 9090},
+	)
+
+	typedResult, err := DefaultPatchMaker.Calculate(current, modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	currentYAML, err := yaml.Marshal(current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modifiedYAML, err := yaml.Marshal(modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scheme := runtime.NewScheme()
+	scheme.AddKnownTypeWithName(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, &testPod{})
+	patchMaker := NewPatchMaker(DefaultAnnotator, &K8sStrategicMergePatcher{}, &BaseJSONMergePatcher{}, WithScheme(scheme)).(*PatchMaker)
+	defaultPatchMaker := DefaultPatchMaker.(*PatchMaker)
+
+	tests := []struct {
+		name          string
+		patchMaker    *PatchMaker
+		hint          TypeHint
+		wantTypedPath bool
+	}{
+		{name: "Go type", patchMaker: defaultPatchMaker, hint: TypeHint{Object: &testPod{}}, wantTypedPath: true},
+		{name: "registered kind", patchMaker: patchMaker, hint: TypeHint{GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: "Pod"}}, wantTypedPath: true},
+		{name: "kind of the document", patchMaker: patchMaker, wantTypedPath: true},
+		{name: "unknown kind", patchMaker: defaultPatchMaker, wantTypedPath: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.patchMaker.CalculateBytes(currentYAML, modifiedYAML, tt.hint)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (string(result.Patch) == string(typedResult.Patch)) != tt.wantTypedPath {
+				t.Fatalf("Unexpected patch %s, the patch of typed objects is %s", result.Patch, typedResult.Patch)
+			}
+
+			mapResult, err := tt.patchMaker.CalculateMap(mustToUnstructured(mustMarshal(current)), mustToUnstructured(mustMarshal(modified)), tt.hint)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(mapResult.Patch) != string(result.Patch) {
+				t.Fatalf("Expected the same patch for maps %s, got %s", result.Patch, mapResult.Patch)
+			}
+		})
+	}
+}
+
+func TestCalculateDocumentsContext(t *testing.T) {
+	annotator := NewAnnotator(LastAppliedConfig, WithConfigHashLabel(ConfigHashLabel))
+	patchMaker := NewPatchMaker(annotator, &K8sStrategicMergePatcher{}, &BaseJSONMergePatcher{}, WithConfigHashFastPath()).(*PatchMaker)
+
+	newConfigMap := func() map[string]interface{} {
+		return map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   map[string]interface{}{"name": "test"},
+			"data":       map[string]interface{}{"key": "value"},
+		}
+	}
+	current := &unstructured.Unstructured{Object: newConfigMap()}
+	if err := annotator.SetLastAppliedAnnotation(current); err != nil {
+		t.Fatal(err)
+	}
+	// The fast path ignores remote changes, so a non-empty patch means it was skipped.
+	unstructured.SetNestedField(current.Object, "changed", "data", "key")
+	currentJSON := mustMarshal(current)
+
+	result, err := patchMaker.CalculateMapContext(context.Background(), current.Object, newConfigMap(), TypeHint{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.IsEmpty() || string(result.Current) != string(currentJSON) {
+		t.Fatalf("Expected the fast path to return an empty patch, got %s", result)
+	}
+	result, err = patchMaker.CalculateBytesContext(context.Background(), currentJSON, mustMarshal(newConfigMap()), TypeHint{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.IsEmpty() {
+		t.Fatalf("Expected the fast path to return an empty patch, got %s", result.Patch)
+	}
+
+	modified := newConfigMap()
+	modified["data"] = map[string]interface{}{"key": "new"}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := patchMaker.CalculateBytesContext(ctx, currentJSON, mustMarshal(modified), TypeHint{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if _, err := patchMaker.CalculateMapContext(ctx, current.Object, modified, TypeHint{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"emperror.dev/errors"
+	json "github.com/json-iterator/go"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+// DetectDrift reports the fields of the last applied configuration that have been changed
+// or removed on the current object since. Fields that are only present on the current object,
+// like defaults set by the API server, are not considered to be drift. The options are applied
+// to the current object and the original configuration the same way as in Calculate.
+// Paths are formatted like the ones in PatchResult.Changes. No drift is reported if the
+// original configuration is not known.
+func (p *PatchMaker) DetectDrift(currentObject runtime.Object, opts ...CalculateOption) ([]string, error) {
+	current, err := json.ConfigCompatibleWithStandardLibrary.Marshal(currentObject)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to convert current object to byte sequence")
+	}
+
+	original, err := p.originalStore.GetOriginalConfiguration(currentObject)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get original configuration")
+	}
+	if original == nil {
+		return nil, nil
+	}
+
+	// The original takes the place of the modified object, so that options remove the same fields from it.
+	for _, opt := range opts {
+		current, original, err = opt(current, original)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to apply option function")
+		}
+	}
+
+	current, err = deleteNullInJsonBytes(current)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to delete null from current object")
+	}
+
+	original, err = deleteNullInJsonBytes(original)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to delete null from original configuration")
+	}
+
+	if restorer, ok := p.originalStore.(originalRestorer); ok {
+		original, err = restorer.restoreOriginal(original, current)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to restore omitted fields of the original configuration")
+		}
+	}
+
+	var originalMap, currentMap map[string]interface{}
+	if err := unmarshalWithNumbers(original, &originalMap); err != nil {
+		return nil, errors.Wrap(err, "Failed to unmarshal original configuration")
+	}
+	if err := unmarshalWithNumbers(current, &currentMap); err != nil {
+		return nil, errors.Wrap(err, "Failed to unmarshal current object")
+	}
+
+	var drifted []string
+	driftInMap("", originalMap, currentMap, p.lookupPatchMeta(currentObject), &drifted)
+	return drifted, nil
+}
+
+func driftInMap(path string, original, current map[string]interface{}, lookupPatchMeta strategicpatch.LookupPatchMeta, drifted *[]string) {
+	keys := make([]string, 0, len(original))
+	for key := range original {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fieldPath := joinChangePath(path, key)
+		currentValue, ok := current[key]
+		if !ok {
+			*drifted = append(*drifted, fieldPath)
+			continue
+		}
+		driftInValue(fieldPath, key, original[key], currentValue, lookupPatchMeta, drifted)
+	}
+}
+
+func driftInValue(path, key string, original, current interface{}, lookupPatchMeta strategicpatch.LookupPatchMeta, drifted *[]string) {
+	switch originalValue := original.(type) {
+	case map[string]interface{}:
+		if currentValue, ok := current.(map[string]interface{}); ok {
+			driftInMap(path, originalValue, currentValue, lookupStructPatchMeta(lookupPatchMeta, key), drifted)
+			return
+		}
+	case []interface{}:
+		if currentValue, ok := current.([]interface{}); ok && isListOfMaps(originalValue) && isListOfMaps(currentValue) {
+			elementPatchMeta, mergeKey := lookupSlicePatchMeta(lookupPatchMeta, key)
+			driftInList(path, mergeKey, originalValue, currentValue, elementPatchMeta, drifted)
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(original, current) {
+		*drifted = append(*drifted, path)
+	}
+}
+
+func driftInList(path, mergeKey string, original, current []interface{}, lookupPatchMeta strategicpatch.LookupPatchMeta, drifted *[]string) {
+	byMergeKey := mergeKey != "" && hasMergeKey(original, mergeKey) && hasMergeKey(current, mergeKey)
+	for i, item := range original {
+		originalElement := item.(map[string]interface{})
+		var elementPath string
+		var currentElement interface{}
+		if byMergeKey {
+			elementPath = fmt.Sprintf("%s[%s=%v]", path, mergeKey, originalElement[mergeKey])
+			currentElement = elementOf(current, mergeKey, originalElement[mergeKey], i)
+		} else {
+			elementPath = fmt.Sprintf("%s[%d]", path, i)
+			currentElement = elementOf(current, "", nil, i)
+		}
+		currentMap, ok := currentElement.(map[string]interface{})
+		if !ok {
+			*drifted = append(*drifted, elementPath)
+			continue
+		}
+		driftInMap(elementPath, originalElement, currentMap, lookupPatchMeta, drifted)
+	}
+}
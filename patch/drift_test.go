@@ -0,0 +1,63 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetectDrift(t *testing.T) {
+	patchMaker := DefaultPatchMaker.(*PatchMaker)
+
+	current := newTestPod(
+		testContainer{Name: "app", Image: "app:1"},
+		testContainer{Name: "sidecar", Image: "sidecar:1"},
+	)
+	if err := DefaultAnnotator.SetLastAppliedAnnotation(current); err != nil {
+		t.Fatal(err)
+	}
+
+	drifted, err := patchMaker.DetectDrift(current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drifted) != 0 {
+		t.Fatalf("Expected no drift, got %v", drifted)
+	}
+
+	// Fields set by others are not drift.
+	current.Spec.Hostname = "defaulted"
+	current.Spec.Containers[0].Args = []string{"--defaulted"}
+	current.Spec.Containers[0].Image = "app:2"
+	current.Spec.Containers = current.Spec.Containers[:1]
+
+	drifted, err = patchMaker.DetectDrift(current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"spec.containers[name=app].image", "spec.containers[name=sidecar]"}
+	if !reflect.DeepEqual(drifted, want) {
+		t.Fatalf("Expected drift %v, got %v", want, drifted)
+	}
+
+	drifted, err = patchMaker.DetectDrift(current, IgnoreField("spec"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drifted) != 0 {
+		t.Fatalf("Expected no drift in ignored fields, got %v", drifted)
+	}
+}
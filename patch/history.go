@@ -0,0 +1,282 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"context"
+	stdjson "encoding/json"
+	"time"
+
+	"emperror.dev/errors"
+	json "github.com/json-iterator/go"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// HistorySuffix is appended to the annotation key of the Annotator
+// to get the key of the annotation that stores the applied configuration history.
+const HistorySuffix = "-history"
+
+// HistoryEntry is a previously applied configuration of an object.
+type HistoryEntry struct {
+	// Revision is increased by one with every recorded configuration.
+	Revision int64 `json:"revision"`
+	// Generation is the metadata.generation of the current object the configuration was applied to,
+	// zero if the object did not exist yet.
+	Generation int64 `json:"generation,omitempty"`
+	// Timestamp is the time the configuration was recorded.
+	Timestamp time.Time `json:"timestamp"`
+	// Configuration is the applied configuration, as it was stored in the last applied annotation.
+	Configuration stdjson.RawMessage `json:"configuration"`
+}
+
+// WithHistory keeps the last limit applied configurations in a separate annotation,
+// as long as the encoded annotation fits into maxBytes. Older entries are dropped first.
+// A maxBytes of zero or less means no byte budget.
+func WithHistory(limit, maxBytes int) AnnotatorOption {
+	return func(a *Annotator) {
+		a.historyLimit = limit
+		a.historyMaxBytes = maxBytes
+	}
+}
+
+func (a *Annotator) historyKey() string {
+	if a.historyLimit <= 0 {
+		return ""
+	}
+	return a.key + HistorySuffix
+}
+
+// GetHistory returns the applied configurations recorded on the object, the most recent one first.
+func (a *Annotator) GetHistory(obj runtime.Object) ([]HistoryEntry, error) {
+	if a.historyLimit <= 0 {
+		return nil, errors.New("history is not enabled on the annotator")
+	}
+
+	annots, err := a.metadataAccessor.Annotations(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := annots[a.historyKey()]
+	if !ok {
+		return nil, nil
+	}
+
+	data, err := decodeAnnotation(value)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decode history annotation")
+	}
+
+	var entries []HistoryEntry
+	if err := json.ConfigCompatibleWithStandardLibrary.Unmarshal(data, &entries); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal history annotation")
+	}
+	return entries, nil
+}
+
+// SetLastAppliedAnnotationWithHistory sets the last applied annotation on the modified object
+// like SetLastAppliedAnnotation does, and records the same configuration on top of the
+// history found on the current object. Current can be nil if the object does not exist yet.
+func (a *Annotator) SetLastAppliedAnnotationWithHistory(current, modified runtime.Object) error {
+	if err := a.SetLastAppliedAnnotation(modified); err != nil {
+		return err
+	}
+
+	if a.historyLimit <= 0 {
+		return nil
+	}
+
+	var history []HistoryEntry
+	if current != nil {
+		var err error
+		history, err = a.GetHistory(current)
+		if err != nil {
+			return err
+		}
+	}
+
+	configuration, err := a.GetOriginalConfiguration(modified)
+	if err != nil {
+		return err
+	}
+
+	entry := HistoryEntry{
+		Timestamp:     time.Now().UTC(),
+		Configuration: configuration,
+	}
+	if len(history) > 0 {
+		entry.Revision = history[0].Revision + 1
+	} else {
+		entry.Revision = 1
+	}
+	// Desired objects carry no generation, the one of the object the configuration is applied to is recorded.
+	if current != nil {
+		if accessor, err := meta.Accessor(current); err == nil {
+			entry.Generation = accessor.GetGeneration()
+		}
+	}
+
+	history = append([]HistoryEntry{entry}, history...)
+	if len(history) > a.historyLimit {
+		history = history[:a.historyLimit]
+	}
+
+	annots, err := a.metadataAccessor.Annotations(modified)
+	if err != nil {
+		return err
+	}
+	if annots == nil {
+		annots = map[string]string{}
+	}
+
+	for ; len(history) > 0; history = history[:len(history)-1] {
+		data, err := json.ConfigCompatibleWithStandardLibrary.Marshal(history)
+		if err != nil {
+			return errors.Wrap(err, "could not marshal history")
+		}
+		encoded, err := zipAndBase64EncodeAnnotation(data)
+		if err != nil {
+			return err
+		}
+		if a.historyMaxBytes <= 0 || len(encoded) <= a.historyMaxBytes {
+			annots[a.historyKey()] = encoded
+			return a.metadataAccessor.SetAnnotations(modified, annots)
+		}
+	}
+
+	// Not even the latest entry fits into the budget.
+	delete(annots, a.historyKey())
+	return a.metadataAccessor.SetAnnotations(modified, annots)
+}
+
+// Rollback calculates the patch that rolls the current object back to the k-th entry
+// of its applied configuration history, where zero is the most recent entry. The patch sets
+// the last applied annotation to the entry too, so that the rollback is not reverted by the next patch.
+// Fields that were excluded from the recorded configuration keep their current value, so do hashed fields,
+// which are listed in PatchResult.UnrecoverablePaths if their current value differs from the recorded one.
+func (p *PatchMaker) Rollback(currentObject runtime.Object, k int, opts ...CalculateOption) (*PatchResult, error) {
+	if p.annotator == nil {
+		return nil, errors.New("Rollback requires the original configuration to be stored by an annotator")
+	}
+
+	history, err := p.annotator.GetHistory(currentObject)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get applied configuration history")
+	}
+	if k < 0 || k >= len(history) {
+		return nil, errors.NewWithDetails("History entry not found", "entry", k, "entries", len(history))
+	}
+
+	current, err := json.ConfigCompatibleWithStandardLibrary.Marshal(currentObject)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to convert current object to byte sequence")
+	}
+
+	unrecoverable, err := p.annotator.changedHashedPaths(history[k].Configuration, current)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to compare hashed fields of the history entry")
+	}
+
+	modified, err := p.annotator.restoreOriginal(history[k].Configuration, current)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to restore omitted fields of the history entry")
+	}
+
+	modified, err = p.annotator.annotateRollback(modified, history[k].Configuration)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to set the last applied annotation to the history entry")
+	}
+
+	result, err := p.calculate(context.Background(), currentObject, current, modified, opts...)
+	if err != nil {
+		return nil, err
+	}
+	result.UnrecoverablePaths = unrecoverable
+	return result, nil
+}
+
+// annotateRollback sets the last applied annotation of the restored configuration to the recorded one,
+// and the config hash label to the hash of the restored configuration.
+func (a *Annotator) annotateRollback(modified, recorded []byte) ([]byte, error) {
+	var modifiedMap map[string]interface{}
+	if err := unmarshalWithNumbers(modified, &modifiedMap); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal restored configuration")
+	}
+
+	annotation, err := zipAndBase64EncodeAnnotation(recorded)
+	if err != nil {
+		return nil, err
+	}
+	if err := unstructured.SetNestedField(modifiedMap, annotation, "metadata", "annotations", a.key); err != nil {
+		return nil, errors.Wrap(err, "could not set last applied annotation")
+	}
+
+	if a.hashLabel != "" {
+		hash, err := ConfigHash(modified)
+		if err != nil {
+			return nil, err
+		}
+		if err := unstructured.SetNestedField(modifiedMap, hash, "metadata", "labels", a.hashLabel); err != nil {
+			return nil, errors.Wrap(err, "could not set config hash label")
+		}
+	}
+
+	return json.ConfigCompatibleWithStandardLibrary.Marshal(modifiedMap)
+}
+
+// changedHashedPaths returns the hashed paths of the recorded configuration
+// whose value in current differs from the recorded one, and so can not be restored.
+func (a *Annotator) changedHashedPaths(recorded, current []byte) ([]string, error) {
+	if len(a.hashedPaths) == 0 {
+		return nil, nil
+	}
+
+	var recordedMap, currentMap map[string]interface{}
+	if err := unmarshalWithNumbers(recorded, &recordedMap); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal recorded configuration")
+	}
+	if err := unmarshalWithNumbers(current, &currentMap); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal current configuration")
+	}
+
+	var paths []string
+	for _, p := range a.hashedPaths {
+		changed := false
+		var err error
+		p.restore(recordedMap, currentMap, func(recorded, current map[string]interface{}, key string) {
+			recordedHash, ok := recorded[key]
+			if !ok || changed || err != nil {
+				return
+			}
+			currentValue, ok := current[key]
+			if !ok {
+				changed = true
+				return
+			}
+			var currentHash string
+			currentHash, err = hashValue(currentValue)
+			changed = currentHash != recordedHash
+		})
+		if err != nil {
+			return nil, errors.WrapWithDetails(err, "could not hash field", "path", p.String())
+		}
+		if changed {
+			paths = append(paths, p.String())
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,163 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newHistoryTestObject(value string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": "test"},
+		"data":       map[string]interface{}{"key": value},
+	}}
+}
+
+func applyHistory(t *testing.T, annotator *Annotator, values ...string) *unstructured.Unstructured {
+	var current *unstructured.Unstructured
+	for i, value := range values {
+		modified := newHistoryTestObject(value)
+		var err error
+		if current == nil {
+			err = annotator.SetLastAppliedAnnotationWithHistory(nil, modified)
+		} else {
+			err = annotator.SetLastAppliedAnnotationWithHistory(current, modified)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The API server bumps the generation on every change.
+		modified.SetGeneration(int64(i + 1))
+		current = modified
+	}
+	return current
+}
+
+func TestHistory(t *testing.T) {
+	annotator := NewAnnotator(LastAppliedConfig, WithHistory(2, 0))
+	current := applyHistory(t, annotator, "v1", "v2", "v3")
+
+	history, err := annotator.GetHistory(current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("Expected 2 history entries, got %d", len(history))
+	}
+	if history[0].Revision != 3 || history[1].Revision != 2 {
+		t.Fatalf("Expected revisions 3 and 2, got %d and %d", history[0].Revision, history[1].Revision)
+	}
+	if history[0].Generation != 2 || history[1].Generation != 1 {
+		t.Fatalf("Expected the generations of the current objects 2 and 1, got %d and %d", history[0].Generation, history[1].Generation)
+	}
+	if history[0].Timestamp.IsZero() {
+		t.Fatal("Expected history entry to have a timestamp")
+	}
+
+	original, err := annotator.GetOriginalConfiguration(current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(original) != string(history[0].Configuration) {
+		t.Fatalf("Expected latest history entry %s to match last applied configuration %s", history[0].Configuration, original)
+	}
+
+	patchMaker := NewPatchMaker(annotator, &K8sStrategicMergePatcher{}, &BaseJSONMergePatcher{}).(*PatchMaker)
+	result, err := patchMaker.Rollback(current, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	patched, err := (&BaseJSONMergePatcher{}).MergePatch(mustMarshal(current), result.Patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rolledBack := &unstructured.Unstructured{Object: mustToUnstructured(patched)}
+	if value, _, _ := unstructured.NestedString(rolledBack.Object, "data", "key"); value != "v2" {
+		t.Fatalf("Expected rollback patch to restore the value, got %s", result.Patch)
+	}
+	// The last applied annotation is rolled back too, otherwise the next patch would revert the rollback.
+	original, err = annotator.GetOriginalConfiguration(rolledBack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(original) != string(history[1].Configuration) {
+		t.Fatalf("Expected the last applied configuration %s after the rollback, got %s", history[1].Configuration, original)
+	}
+	if result.UnrecoverablePaths != nil {
+		t.Fatalf("Expected no unrecoverable paths, got %v", result.UnrecoverablePaths)
+	}
+
+	result, err = patchMaker.Rollback(current, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.IsEmpty() {
+		t.Fatalf("Expected empty rollback patch to the latest entry, got %s", result.Patch)
+	}
+
+	if _, err := patchMaker.Rollback(current, 2); err == nil {
+		t.Fatal("Expected error for missing history entry")
+	}
+}
+
+func TestRollbackHashedPaths(t *testing.T) {
+	annotator := NewAnnotator(LastAppliedConfig, WithHistory(3, 0), WithHashedPaths("data.key"))
+	current := applyHistory(t, annotator, "v1", "v2")
+	patchMaker := NewPatchMaker(annotator, &K8sStrategicMergePatcher{}, &BaseJSONMergePatcher{}).(*PatchMaker)
+
+	result, err := patchMaker.Rollback(current, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.UnrecoverablePaths, []string{"data.key"}) {
+		t.Fatalf("Expected the hashed path to be reported, got %v", result.UnrecoverablePaths)
+	}
+
+	result, err = patchMaker.Rollback(current, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.UnrecoverablePaths != nil {
+		t.Fatalf("Expected unchanged hashed paths not to be reported, got %v", result.UnrecoverablePaths)
+	}
+}
+
+func TestHistoryByteBudget(t *testing.T) {
+	unbounded := NewAnnotator(LastAppliedConfig, WithHistory(10, 0))
+	single := applyHistory(t, unbounded, "v1")
+	budget := len(single.GetAnnotations()[LastAppliedConfig+HistorySuffix])
+
+	// The entries recorded later carry a generation as well, which the margin leaves room for.
+	annotator := NewAnnotator(LastAppliedConfig, WithHistory(10, budget+30))
+	current := applyHistory(t, annotator, "v1", "v2", "v3")
+	history, err := annotator.GetHistory(current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].Revision != 3 {
+		t.Fatalf("Expected only the latest entry to fit into the budget, got %d entries", len(history))
+	}
+
+	annotator = NewAnnotator(LastAppliedConfig, WithHistory(10, 1))
+	current = applyHistory(t, annotator, "v1")
+	if _, ok := current.GetAnnotations()[LastAppliedConfig+HistorySuffix]; ok {
+		t.Fatal("Expected no history annotation when no entry fits into the budget")
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// RecreateStrategy tells how an object has to be recreated to change its immutable fields.
+type RecreateStrategy string
+
+const (
+	// RecreateDelete deletes the object together with its dependents, then creates it again.
+	RecreateDelete RecreateStrategy = "delete"
+	// RecreateOrphan deletes the object with the orphan propagation policy, keeping its dependents,
+	// like the pods and volumes of a StatefulSet, then creates it again to adopt them.
+	RecreateOrphan RecreateStrategy = "orphan"
+)
+
+// DefaultImmutableFields lists the immutable fields of built-in kinds that are commonly changed by mistake.
+var DefaultImmutableFields = NewImmutableFields(
+	ImmutableKindPaths(schema.GroupVersionKind{Group: "apps", Kind: "StatefulSet"}, RecreateOrphan, "spec.selector", "spec.volumeClaimTemplates"),
+	ImmutableKindPaths(schema.GroupVersionKind{Kind: "Service"}, RecreateDelete, "spec.clusterIP"),
+	ImmutableKindPaths(schema.GroupVersionKind{Group: "batch", Kind: "Job"}, RecreateDelete, "spec.template"),
+	ImmutableKindPaths(schema.GroupVersionKind{Kind: "PersistentVolumeClaim"}, RecreateDelete, "spec.storageClassName"),
+)
+
+// ImmutableFields is a registry of the fields the API server rejects changes to, by the kind of the objects.
+type ImmutableFields struct {
+	kinds []immutableKindFields
+}
+
+type immutableKindFields struct {
+	gvk      schema.GroupVersionKind
+	recreate RecreateStrategy
+	paths    []fieldPath
+}
+
+type ImmutableFieldsOption func(*ImmutableFields)
+
+// NewImmutableFields creates a registry with the given rules. Without rules no field is immutable.
+func NewImmutableFields(opts ...ImmutableFieldsOption) *ImmutableFields {
+	f := &ImmutableFields{}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// With returns a copy of the registry extended with the given rules, like the immutable fields of custom resources.
+func (f *ImmutableFields) With(opts ...ImmutableFieldsOption) *ImmutableFields {
+	extended := &ImmutableFields{kinds: append([]immutableKindFields{}, f.kinds...)}
+	for _, opt := range opts {
+		opt(extended)
+	}
+	return extended
+}
+
+// ImmutableKindPaths registers immutable fields of the given kind, and how the objects have to be recreated to change them.
+// An empty version matches every version of the kind. Paths are dot separated, "[]" selects every element of a list.
+// It panics if a path is invalid.
+func ImmutableKindPaths(gvk schema.GroupVersionKind, recreate RecreateStrategy, paths ...string) ImmutableFieldsOption {
+	return func(f *ImmutableFields) {
+		f.kinds = append(f.kinds, immutableKindFields{gvk: gvk, recreate: recreate, paths: mustParseFieldPaths(paths)})
+	}
+}
+
+// WithImmutableFields sets the registry used to find the changes to immutable fields, see PatchResult.ImmutableFieldChanges.
+// DefaultImmutableFields is used if not set, NewImmutableFields() without rules turns detection off.
+// The changes are only looked for if the patch maker lists them, see WithChanges.
+func WithImmutableFields(immutableFields *ImmutableFields) PatchMakerOption {
+	return func(p *PatchMaker) {
+		p.immutableFields = immutableFields
+	}
+}
+
+// RequiresRecreate tells whether the patch changes immutable fields, so the object
+// has to be recreated as told by Recreate instead of being patched.
+func (p *PatchResult) RequiresRecreate() bool {
+	return p.Recreate != ""
+}
+
+// setImmutableFieldChanges finds the changes of the result to immutable fields of the object.
+func (p *PatchMaker) setImmutableFieldChanges(result *PatchResult, obj runtime.Object) {
+	immutableFields := p.immutableFields
+	if immutableFields == nil {
+		immutableFields = DefaultImmutableFields
+	}
+	if len(immutableFields.kinds) == 0 || len(result.Changes) == 0 {
+		return
+	}
+
+	gvk := p.objectGroupVersionKind(obj)
+	for _, kind := range immutableFields.kinds {
+		if !kind.matches(gvk) {
+			continue
+		}
+		for _, change := range result.Changes {
+			if !kind.changes(change.Path) {
+				continue
+			}
+			result.ImmutableFieldChanges = append(result.ImmutableFieldChanges, change)
+			if result.Recreate != RecreateDelete {
+				result.Recreate = kind.recreate
+			}
+		}
+	}
+}
+
+// objectGroupVersionKind returns the group, version and kind of the object. Typed objects returned by clients
+// usually have no kind set, they are looked up in the scheme of the patch maker or named after their Go type.
+func (p *PatchMaker) objectGroupVersionKind(obj runtime.Object) schema.GroupVersionKind {
+	if gvk := obj.GetObjectKind().GroupVersionKind(); gvk.Kind != "" {
+		return gvk
+	}
+	if p.scheme != nil {
+		if gvks, _, err := p.scheme.ObjectKinds(obj); err == nil && len(gvks) > 0 {
+			return gvks[0]
+		}
+	}
+	return schema.GroupVersionKind{Kind: objectKind(obj)}
+}
+
+// matches tells whether the rule applies to objects of the given kind.
+// The group and the version are not compared for objects without them.
+func (k immutableKindFields) matches(gvk schema.GroupVersionKind) bool {
+	if k.gvk.Kind != gvk.Kind {
+		return false
+	}
+	if gvk.Version == "" {
+		return true
+	}
+	return k.gvk.Group == gvk.Group && (k.gvk.Version == "" || k.gvk.Version == gvk.Version)
+}
+
+// changes tells whether a change at the given path, like "spec.template.spec.containers[name=app].image",
+// changes any of the immutable fields.
+func (k immutableKindFields) changes(changePath string) bool {
+	normalized := normalizeChangePath(changePath)
+	for _, path := range k.paths {
+		p := path.String()
+		if normalized == p || strings.HasPrefix(normalized, p+".") || strings.HasPrefix(normalized, p+"[]") {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeChangePath removes the element selectors from a change path, like "spec.containers[name=app].image"
+// becoming "spec.containers[].image", so that it can be compared with field paths.
+func normalizeChangePath(changePath string) string {
+	var b strings.Builder
+	depth := 0
+	for _, r := range changePath {
+		switch {
+		case r == '[':
+			if depth == 0 {
+				b.WriteRune(r)
+			}
+			depth++
+		case r == ']' && depth > 0:
+			depth--
+			if depth == 0 {
+				b.WriteRune(r)
+			}
+		case depth == 0:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
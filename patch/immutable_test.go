@@ -0,0 +1,144 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestImmutableFields(t *testing.T) {
+	newObject := func(apiVersion, kind string, spec map[string]interface{}) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": apiVersion,
+			"kind":       kind,
+			"metadata":   map[string]interface{}{"name": "test", "namespace": "default"},
+			"spec":       spec,
+		}}
+	}
+	statefulSet := func(app, serviceName string) *unstructured.Unstructured {
+		return newObject("apps/v1", "StatefulSet", map[string]interface{}{
+			"selector":    map[string]interface{}{"matchLabels": map[string]interface{}{"app": app}},
+			"serviceName": serviceName,
+		})
+	}
+
+	widgets := DefaultImmutableFields.With(
+		ImmutableKindPaths(schema.GroupVersionKind{Group: "example.com", Kind: "Widget"}, RecreateDelete, "spec.parts[].name"),
+		ImmutableKindPaths(schema.GroupVersionKind{Kind: "testPod"}, RecreateOrphan, "spec.hostname"),
+	)
+
+	tests := []struct {
+		name            string
+		immutableFields *ImmutableFields
+		current         runtime.Object
+		modified        runtime.Object
+		wantPaths       []string
+		wantRecreate    RecreateStrategy
+	}{
+		{
+			name:     "mutable field",
+			current:  statefulSet("a", "a"),
+			modified: statefulSet("a", "b"),
+		},
+		{
+			name:         "statefulset selector",
+			current:      statefulSet("a", "a"),
+			modified:     statefulSet("b", "b"),
+			wantPaths:    []string{"spec.selector.matchLabels.app"},
+			wantRecreate: RecreateOrphan,
+		},
+		{
+			name:         "service cluster IP",
+			current:      newObject("v1", "Service", map[string]interface{}{"clusterIP": "10.0.0.1"}),
+			modified:     newObject("v1", "Service", map[string]interface{}{"clusterIP": "10.0.0.2"}),
+			wantPaths:    []string{"spec.clusterIP"},
+			wantRecreate: RecreateDelete,
+		},
+		{
+			name:     "other group",
+			current:  newObject("example.com/v1", "Service", map[string]interface{}{"clusterIP": "10.0.0.1"}),
+			modified: newObject("example.com/v1", "Service", map[string]interface{}{"clusterIP": "10.0.0.2"}),
+		},
+		{
+			name:     "detection turned off",
+			current:  newObject("v1", "Service", map[string]interface{}{"clusterIP": "10.0.0.1"}),
+			modified: newObject("v1", "Service", map[string]interface{}{"clusterIP": "10.0.0.2"}),
+
+			immutableFields: NewImmutableFields(),
+		},
+		{
+			name:            "custom resource",
+			immutableFields: widgets,
+			current:         newTestWidget(map[string]interface{}{"name": "a"}),
+			modified:        newTestWidget(map[string]interface{}{"name": "b"}),
+			wantPaths:       []string{"spec.parts[0].name"},
+			wantRecreate:    RecreateDelete,
+		},
+		{
+			name:            "typed object without kind",
+			immutableFields: widgets,
+			current:         &testPod{Spec: testPodSpec{Hostname: "a"}},
+			modified:        &testPod{Spec: testPodSpec{Hostname: "b"}},
+			wantPaths:       []string{"spec.hostname"},
+			wantRecreate:    RecreateOrphan,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := []PatchMakerOption{WithChanges()}
+			if tt.immutableFields != nil {
+				opts = append(opts, WithImmutableFields(tt.immutableFields))
+			}
+			patchMaker := NewPatchMaker(DefaultAnnotator, &K8sStrategicMergePatcher{}, &BaseJSONMergePatcher{}, opts...)
+
+			result, err := patchMaker.Calculate(tt.current, tt.modified)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.IsEmpty() {
+				t.Fatal("Expected a non-empty patch")
+			}
+
+			var paths []string
+			for _, change := range result.ImmutableFieldChanges {
+				paths = append(paths, change.Path)
+			}
+			if len(paths) != len(tt.wantPaths) || (len(paths) > 0 && paths[0] != tt.wantPaths[0]) {
+				t.Fatalf("Expected immutable field changes %v, got %v", tt.wantPaths, paths)
+			}
+			if result.Recreate != tt.wantRecreate || result.RequiresRecreate() != (tt.wantRecreate != "") {
+				t.Fatalf("Expected recreate strategy %q, got %q", tt.wantRecreate, result.Recreate)
+			}
+		})
+	}
+}
+
+func TestNormalizeChangePath(t *testing.T) {
+	for path, want := range map[string]string{
+		"spec.clusterIP":                           "spec.clusterIP",
+		"spec.containers[name=app].image":          "spec.containers[].image",
+		"spec.volumeClaimTemplates[0]":             "spec.volumeClaimTemplates[]",
+		"spec.ports[name=a[b]].targetPort":         "spec.ports[].targetPort",
+		"spec.template.spec.containers[1].args[0]": "spec.template.spec.containers[].args[]",
+	} {
+		if got := normalizeChangePath(path); got != want {
+			t.Errorf("normalizeChangePath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
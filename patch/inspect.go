@@ -0,0 +1,92 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"bytes"
+	stdjson "encoding/json"
+	"fmt"
+
+	"emperror.dev/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// AnnotationFormat is the encoding of the last applied annotation.
+type AnnotationFormat string
+
+const (
+	// AnnotationFormatRaw is plain JSON, as written by old versions of the library.
+	AnnotationFormatRaw AnnotationFormat = "raw"
+	// AnnotationFormatBase64 is base64 encoded JSON.
+	AnnotationFormatBase64 AnnotationFormat = "base64"
+	// AnnotationFormatZip is a base64 encoded zip archive holding the JSON, the current format.
+	AnnotationFormatZip AnnotationFormat = "zip"
+)
+
+// AnnotationInfo describes the last applied annotation of an object.
+type AnnotationInfo struct {
+	Key    string
+	Format AnnotationFormat
+	// EncodedSize is the length of the annotation value in bytes.
+	EncodedSize int
+	// DecodedSize is the length of the decoded original configuration in bytes.
+	DecodedSize int
+	// CompressionRatio is DecodedSize divided by EncodedSize.
+	CompressionRatio float64
+	// Original is the indented original configuration, or the decoded bytes as is if they are not valid JSON.
+	Original string
+}
+
+func (i *AnnotationInfo) String() string {
+	return fmt.Sprintf("Key: %s\nFormat: %s\nEncoded size: %d\nDecoded size: %d\nCompression ratio: %.2f\nOriginal:\n%s\n",
+		i.Key, i.Format, i.EncodedSize, i.DecodedSize, i.CompressionRatio, i.Original)
+}
+
+// Inspect decodes the last applied annotation of the object for debugging purposes,
+// or returns nil if no annotation was found.
+func (a *Annotator) Inspect(obj runtime.Object) (*AnnotationInfo, error) {
+	annots, err := a.metadataAccessor.Annotations(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := annots[a.key]
+	if !ok {
+		return nil, nil
+	}
+
+	decoded, format, err := decodeAnnotationWithFormat(value)
+	if err != nil {
+		return nil, errors.WrapWithDetails(err, "could not decode annotation", "format", format)
+	}
+
+	info := &AnnotationInfo{
+		Key:         a.key,
+		Format:      format,
+		EncodedSize: len(value),
+		DecodedSize: len(decoded),
+		Original:    string(decoded),
+	}
+	if info.EncodedSize > 0 {
+		info.CompressionRatio = float64(info.DecodedSize) / float64(info.EncodedSize)
+	}
+
+	var indented bytes.Buffer
+	if err := stdjson.Indent(&indented, decoded, "", "  "); err == nil {
+		info.Original = indented.String()
+	}
+
+	return info, nil
+}
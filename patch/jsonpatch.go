@@ -0,0 +1,172 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"emperror.dev/errors"
+)
+
+// JSONPatchOperation is a single operation of an RFC 6902 JSON Patch.
+type JSONPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+type JSONPatchOption func(*jsonPatchOptions)
+
+type jsonPatchOptions struct {
+	testResourceVersion bool
+	redacted            bool
+}
+
+// WithResourceVersionTest starts the JSON Patch with a test operation on the resourceVersion
+// of the current object, so that the patch is rejected if the object has been changed since.
+func WithResourceVersionTest() JSONPatchOption {
+	return func(o *jsonPatchOptions) {
+		o.testResourceVersion = true
+	}
+}
+
+// WithRedactedValues redacts the sensitive values of the operations, to be able to log them, see WithRedactor.
+// Such a JSON Patch must not be sent to the API server.
+func WithRedactedValues() JSONPatchOption {
+	return func(o *jsonPatchOptions) {
+		o.redacted = true
+	}
+}
+
+// JSONPatch returns the RFC 6902 JSON Patch operations that have the same effect on Current as Patch.
+// Sensitive values are only redacted if WithRedactedValues is given.
+func (p *PatchResult) JSONPatch(opts ...JSONPatchOption) ([]JSONPatchOperation, error) {
+	options := &jsonPatchOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	operations := []JSONPatchOperation{}
+
+	if options.testResourceVersion {
+		var current map[string]interface{}
+		if err := unmarshalWithNumbers(p.Current, &current); err != nil {
+			return nil, errors.Wrap(err, "could not unmarshal current object")
+		}
+		metadata, _ := current["metadata"].(map[string]interface{})
+		resourceVersion, ok := metadata["resourceVersion"]
+		if !ok {
+			return nil, errors.New("current object has no resourceVersion to test")
+		}
+		operations = append(operations, JSONPatchOperation{Op: "test", Path: "/metadata/resourceVersion", Value: resourceVersion})
+	}
+
+	if p.IsEmpty() {
+		return operations, nil
+	}
+	patchedData, err := p.getPatched()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not apply patch")
+	}
+	if patchedData == nil {
+		return nil, errors.New("patch result has no patched object to compare with")
+	}
+
+	currentData := p.Current
+	if options.redacted {
+		currentData, patchedData, err = p.getRedactor().redactPair(currentData, patchedData, p.kind)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not redact sensitive values")
+		}
+	}
+
+	var current, patched interface{}
+	if err := unmarshalWithNumbers(currentData, &current); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal current object")
+	}
+	if err := unmarshalWithNumbers(patchedData, &patched); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal patched object")
+	}
+
+	return diffJSONPatch("", current, patched, operations), nil
+}
+
+func diffJSONPatch(pointer string, old, new interface{}, operations []JSONPatchOperation) []JSONPatchOperation {
+	if reflect.DeepEqual(old, new) {
+		return operations
+	}
+
+	switch oldValue := old.(type) {
+	case map[string]interface{}:
+		newValue, ok := new.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(oldValue)+len(newValue))
+		for key := range oldValue {
+			keys = append(keys, key)
+		}
+		for key := range newValue {
+			if _, ok := oldValue[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			childPointer := pointer + "/" + escapeJSONPointer(key)
+			o, inOld := oldValue[key]
+			n, inNew := newValue[key]
+			switch {
+			case !inNew:
+				operations = append(operations, JSONPatchOperation{Op: "remove", Path: childPointer})
+			case !inOld:
+				operations = append(operations, JSONPatchOperation{Op: "add", Path: childPointer, Value: n})
+			default:
+				operations = diffJSONPatch(childPointer, o, n, operations)
+			}
+		}
+		return operations
+	case []interface{}:
+		newValue, ok := new.([]interface{})
+		if !ok {
+			break
+		}
+		// Elements are changed in place, then the tail of the list is extended or truncated,
+		// from the end so that the indexes of the remaining operations stay valid.
+		common := len(oldValue)
+		if len(newValue) < common {
+			common = len(newValue)
+		}
+		for i := 0; i < common; i++ {
+			operations = diffJSONPatch(pointer+"/"+strconv.Itoa(i), oldValue[i], newValue[i], operations)
+		}
+		for i := common; i < len(newValue); i++ {
+			operations = append(operations, JSONPatchOperation{Op: "add", Path: pointer + "/" + strconv.Itoa(i), Value: newValue[i]})
+		}
+		for i := len(oldValue) - 1; i >= common; i-- {
+			operations = append(operations, JSONPatchOperation{Op: "remove", Path: pointer + "/" + strconv.Itoa(i)})
+		}
+		return operations
+	}
+
+	return append(operations, JSONPatchOperation{Op: "replace", Path: pointer, Value: new})
+}
+
+func escapeJSONPointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,125 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"reflect"
+	"testing"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+func TestJSONPatchRoundTrip(t *testing.T) {
+	newUnstructured := func(labels map[string]interface{}, items ...interface{}) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "example.com/v1",
+			"kind":       "Example",
+			"metadata":   map[string]interface{}{"name": "test", "labels": labels},
+			"spec":       map[string]interface{}{"items": items, "enabled": true},
+		}}
+	}
+
+	tests := []struct {
+		name     string
+		current  runtime.Object
+		modified runtime.Object
+	}{
+		{
+			name:     "strategic merge patch",
+			current:  newTestPod(testContainer{Name: "a", Image: "a:1"}, testContainer{Name: "b", Image: "b:1", Args: []string{"x", "y"}}),
+			modified: newTestPod(testContainer{Name: "c", Image: "c:1"}, testContainer{Name: "b", Image: "b:2", Args: []string{"y"}}),
+		},
+		{
+			name:     "json merge patch",
+			current:  newUnstructured(map[string]interface{}{"app.kubernetes.io/name": "a", "x~y": "b"}, "a", "b", "c"),
+			modified: newUnstructured(map[string]interface{}{"app.kubernetes.io/name": "b"}, "a", false),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := DefaultAnnotator.SetLastAppliedAnnotation(tt.current); err != nil {
+				t.Fatal(err)
+			}
+			result, err := DefaultPatchMaker.Calculate(tt.current, tt.modified)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.IsEmpty() {
+				t.Fatal("Expected a non-empty patch")
+			}
+
+			var expected []byte
+			if _, ok := tt.current.(*unstructured.Unstructured); ok {
+				expected, err = jsonpatch.MergePatch(result.Current, result.Patch)
+			} else {
+				expected, err = strategicpatch.StrategicMergePatch(result.Current, result.Patch, tt.current)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			operations, err := result.JSONPatch()
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := jsonpatch.DecodePatch(mustMarshal(operations))
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual, err := decoded.Apply(result.Current)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(mustToUnstructured(actual), mustToUnstructured(expected)) {
+				t.Fatalf("JSON Patch %s resulted in %s, want %s", mustMarshal(operations), actual, expected)
+			}
+		})
+	}
+}
+
+func TestJSONPatchResourceVersionTest(t *testing.T) {
+	current := newTestPod(testContainer{Name: "a", Image: "a:1"})
+	current.ResourceVersion = "42"
+	result, err := DefaultPatchMaker.Calculate(current, newTestPod(testContainer{Name: "a", Image: "a:2"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	operations, err := result.JSONPatch(WithResourceVersionTest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []JSONPatchOperation{
+		{Op: "test", Path: "/metadata/resourceVersion", Value: "42"},
+		{Op: "replace", Path: "/spec/containers/0/image", Value: "a:2"},
+	}
+	if !reflect.DeepEqual(operations, want) {
+		t.Fatalf("Expected operations %v, got %v", want, operations)
+	}
+
+	decoded, err := jsonpatch.DecodePatch(mustMarshal(operations))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale := mustToUnstructured(result.Current)
+	stale["metadata"].(map[string]interface{})["resourceVersion"] = "41"
+	if _, err := decoded.Apply(mustFromUnstructured(stale)); err == nil {
+		t.Fatal("Expected the patch to fail on a stale resourceVersion")
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"context"
+	"fmt"
+
+	"emperror.dev/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ContextMaker is a Maker that can give up the calculation when its context is done.
+type ContextMaker interface {
+	Maker
+	CalculateContext(ctx context.Context, currentObject, modifiedObject runtime.Object, opts ...CalculateOption) (*PatchResult, error)
+}
+
+var _ ContextMaker = &PatchMaker{}
+
+// SizeLimitError is returned when an object is too large to calculate a patch for, see WithSizeLimit.
+type SizeLimitError struct {
+	// Object is the object that is too large: current, modified or original.
+	Object string
+	Size   int
+	Limit  int
+}
+
+func (e *SizeLimitError) Error() string {
+	return fmt.Sprintf("%s object is %d bytes, which exceeds the limit of %d bytes", e.Object, e.Size, e.Limit)
+}
+
+// WithSizeLimit makes the calculation fail with a SizeLimitError if the JSON representation
+// of the current, the modified or the original object is larger than maxBytes.
+func WithSizeLimit(maxBytes int) PatchMakerOption {
+	return func(p *PatchMaker) {
+		p.sizeLimit = maxBytes
+	}
+}
+
+func (p *PatchMaker) checkSize(object string, data []byte) error {
+	if p.sizeLimit > 0 && len(data) > p.sizeLimit {
+		return errors.WithStack(&SizeLimitError{Object: object, Size: len(data), Limit: p.sizeLimit})
+	}
+	return nil
+}
+
+func checkContext(ctx context.Context, phase string) error {
+	if err := ctx.Err(); err != nil {
+		return errors.WrapWithDetails(err, "Calculation stopped", "phase", phase)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"context"
+	"testing"
+
+	"emperror.dev/errors"
+)
+
+func TestCalculateContextCancelled(t *testing.T) {
+	patchMaker := DefaultPatchMaker.(ContextMaker)
+
+	current := newTestPod(testContainer{Name: "app", Image: "app:1"})
+	modified := newTestPod(testContainer{Name: "app", Image: "app:2"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := patchMaker.CalculateContext(ctx, current, modified)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+
+	result, err := patchMaker.CalculateContext(context.Background(), current, modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.IsEmpty() {
+		t.Fatal("Expected a non-empty patch")
+	}
+}
+
+func TestSizeLimit(t *testing.T) {
+	patchMaker := NewPatchMaker(DefaultAnnotator, &K8sStrategicMergePatcher{}, &BaseJSONMergePatcher{}, WithSizeLimit(200))
+
+	current := newTestPod(testContainer{Name: "app", Image: "app:1"})
+	modified := newTestPod(testContainer{Name: "app", Image: "app:1", Args: []string{"--a-rather-long-argument", "--and-another-long-argument"}})
+
+	if _, err := patchMaker.Calculate(current, current); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := patchMaker.Calculate(current, modified)
+	var sizeLimitError *SizeLimitError
+	if !errors.As(err, &sizeLimitError) {
+		t.Fatalf("Expected a SizeLimitError, got %v", err)
+	}
+	if sizeLimitError.Object != "modified" || sizeLimitError.Limit != 200 {
+		t.Fatalf("Unexpected error %+v", sizeLimitError)
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"emperror.dev/errors"
+	json "github.com/json-iterator/go"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const fieldsV1Type = "FieldsV1"
+
+// ManagedFieldsStore is an OriginalStore for objects managed with server-side apply,
+// where no last applied annotation exists. It reconstructs the original configuration
+// from the fields owned by a field manager, projected from the current object.
+type ManagedFieldsStore struct {
+	manager string
+}
+
+func NewManagedFieldsStore(manager string) *ManagedFieldsStore {
+	return &ManagedFieldsStore{
+		manager: manager,
+	}
+}
+
+// GetOriginalConfiguration returns the fields of the object that are owned by the field manager,
+// or nil if the manager owns no fields of the object.
+func (s *ManagedFieldsStore) GetOriginalConfiguration(obj runtime.Object) ([]byte, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	var owned map[string]interface{}
+	for _, entry := range accessor.GetManagedFields() {
+		if entry.Manager != s.manager || entry.FieldsType != fieldsV1Type || entry.FieldsV1 == nil {
+			continue
+		}
+		var fields map[string]interface{}
+		if err := unmarshalWithNumbers(entry.FieldsV1.Raw, &fields); err != nil {
+			return nil, errors.WrapWithDetails(err, "could not unmarshal managed fields", "manager", s.manager)
+		}
+		owned = mergeFieldSets(owned, fields)
+	}
+	if owned == nil {
+		return nil, nil
+	}
+
+	current, err := json.ConfigCompatibleWithStandardLibrary.Marshal(obj)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not marshal current object")
+	}
+	var currentMap map[string]interface{}
+	if err := unmarshalWithNumbers(current, &currentMap); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal current object")
+	}
+
+	projected, err := projectFields(currentMap, owned)
+	if err != nil {
+		return nil, errors.WrapWithDetails(err, "could not project managed fields", "manager", s.manager)
+	}
+	return json.ConfigCompatibleWithStandardLibrary.Marshal(projected)
+}
+
+func mergeFieldSets(a, b map[string]interface{}) map[string]interface{} {
+	if a == nil {
+		return b
+	}
+	for key, value := range b {
+		existing, _ := a[key].(map[string]interface{})
+		child, _ := value.(map[string]interface{})
+		a[key] = mergeFieldSets(existing, child)
+		if a[key] == nil {
+			a[key] = map[string]interface{}{}
+		}
+	}
+	return a
+}
+
+// projectFields returns the parts of the value selected by a FieldsV1 set.
+// Keys of the set are "f:<field>" for map fields, "k:<keys>" for list elements
+// identified by their merge keys, "v:<value>" for set elements, "i:<index>" for
+// list elements identified by their position and "." for the value itself.
+func projectFields(value interface{}, fields map[string]interface{}) (interface{}, error) {
+	if isLeafFieldSet(fields) {
+		return value, nil
+	}
+
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		projected := map[string]interface{}{}
+		for key, child := range fields {
+			if !strings.HasPrefix(key, "f:") {
+				continue
+			}
+			name := strings.TrimPrefix(key, "f:")
+			v, ok := typedValue[name]
+			if !ok {
+				continue
+			}
+			childFields, _ := child.(map[string]interface{})
+			p, err := projectFields(v, childFields)
+			if err != nil {
+				return nil, err
+			}
+			projected[name] = p
+		}
+		return projected, nil
+	case []interface{}:
+		projected := []interface{}{}
+		for i, item := range typedValue {
+			childFields, err := listElementFields(item, i, fields)
+			if err != nil {
+				return nil, err
+			}
+			if childFields == nil {
+				continue
+			}
+			p, err := projectFields(item, childFields)
+			if err != nil {
+				return nil, err
+			}
+			projected = append(projected, p)
+		}
+		return projected, nil
+	default:
+		return value, nil
+	}
+}
+
+func isLeafFieldSet(fields map[string]interface{}) bool {
+	for key := range fields {
+		if key != "." {
+			return false
+		}
+	}
+	return true
+}
+
+// listElementFields returns the field set of the list element, or nil if the element is not in the set.
+func listElementFields(item interface{}, index int, fields map[string]interface{}) (map[string]interface{}, error) {
+	for key, child := range fields {
+		childFields, _ := child.(map[string]interface{})
+		if childFields == nil {
+			childFields = map[string]interface{}{}
+		}
+		switch {
+		case strings.HasPrefix(key, "k:"):
+			var keys map[string]interface{}
+			if err := unmarshalWithNumbers([]byte(strings.TrimPrefix(key, "k:")), &keys); err != nil {
+				return nil, errors.WrapWithDetails(err, "invalid list element key", "key", key)
+			}
+			element, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			matches := true
+			for k, v := range keys {
+				if !reflect.DeepEqual(element[k], v) {
+					matches = false
+					break
+				}
+			}
+			if matches {
+				return childFields, nil
+			}
+		case strings.HasPrefix(key, "v:"):
+			var v interface{}
+			if err := unmarshalWithNumbers([]byte(strings.TrimPrefix(key, "v:")), &v); err != nil {
+				return nil, errors.WrapWithDetails(err, "invalid list element value", "key", key)
+			}
+			if reflect.DeepEqual(item, v) {
+				return childFields, nil
+			}
+		case strings.HasPrefix(key, "i:"):
+			i, err := strconv.Atoi(strings.TrimPrefix(key, "i:"))
+			if err != nil {
+				return nil, errors.WrapWithDetails(err, "invalid list element index", "key", key)
+			}
+			if i == index {
+				return childFields, nil
+			}
+		}
+	}
+	return nil, nil
+}
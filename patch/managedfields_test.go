@@ -0,0 +1,99 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newManagedFieldsTestObject() *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Service",
+		"metadata": map[string]interface{}{
+			"name":   "test",
+			"labels": map[string]interface{}{"app": "test", "other": "value"},
+		},
+		"spec": map[string]interface{}{
+			"clusterIP": "10.0.0.1",
+			"ports": []interface{}{
+				map[string]interface{}{"port": int64(80), "protocol": "TCP", "name": "http"},
+				map[string]interface{}{"port": int64(443), "protocol": "TCP", "name": "https"},
+			},
+		},
+	}}
+	u.SetManagedFields([]metav1.ManagedFieldsEntry{
+		{
+			Manager:    "operator",
+			Operation:  metav1.ManagedFieldsOperationApply,
+			FieldsType: "FieldsV1",
+			FieldsV1: &metav1.FieldsV1{Raw: []byte(`{
+				"f:metadata":{"f:labels":{"f:app":{}}},
+				"f:spec":{"f:ports":{"k:{\"port\":80,\"protocol\":\"TCP\"}":{".":{},"f:name":{},"f:port":{},"f:protocol":{}}}}
+			}`)},
+		},
+		{
+			Manager:    "kube-controller-manager",
+			Operation:  metav1.ManagedFieldsOperationUpdate,
+			FieldsType: "FieldsV1",
+			FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:clusterIP":{}}}`)},
+		},
+	})
+	return u
+}
+
+func TestManagedFieldsStore(t *testing.T) {
+	store := NewManagedFieldsStore("operator")
+
+	original, err := store.GetOriginalConfiguration(newManagedFieldsTestObject())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"metadata": map[string]interface{}{"labels": map[string]interface{}{"app": "test"}},
+		"spec": map[string]interface{}{"ports": []interface{}{
+			map[string]interface{}{"port": float64(80), "protocol": "TCP", "name": "http"},
+		}},
+	}
+	if !reflect.DeepEqual(mustToUnstructured(original), want) {
+		t.Fatalf("Expected original %v, got %s", want, original)
+	}
+
+	original, err = NewManagedFieldsStore("unknown").GetOriginalConfiguration(newManagedFieldsTestObject())
+	if err != nil || original != nil {
+		t.Fatalf("Expected no original for unknown manager, got %s, %v", original, err)
+	}
+
+	patchMaker := NewPatchMakerWithOriginalStore(store, &K8sStrategicMergePatcher{}, &BaseJSONMergePatcher{})
+	modified := newManagedFieldsTestObject()
+	modified.SetManagedFields(nil)
+	unstructured.RemoveNestedField(modified.Object, "metadata", "labels", "app")
+	result, err := patchMaker.Calculate(newManagedFieldsTestObject(), modified, IgnoreField("spec"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantPatch := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{"app": nil},
+		},
+	}
+	if !reflect.DeepEqual(mustToUnstructured(result.Patch), wantPatch) {
+		t.Fatalf("Expected patch %v, got %s", wantPatch, result.Patch)
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"fmt"
+
+	"emperror.dev/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// MissingOriginalPolicy tells what Calculate does when the original configuration of an object is not known,
+// like for objects created by other tools.
+type MissingOriginalPolicy string
+
+const (
+	// MissingOriginalIgnoreAdditions runs the three-way merge with an empty original. Fields missing from
+	// the modified object are never removed, as they are considered to be added by others. This is the default.
+	MissingOriginalIgnoreAdditions MissingOriginalPolicy = "ignore-additions"
+	// MissingOriginalUseCurrent runs the three-way merge with the current object as the original,
+	// so every field missing from the modified object is removed, except the ones populated by the server,
+	// like metadata.uid, metadata.resourceVersion and status. The current object is reported as PatchResult.Original.
+	MissingOriginalUseCurrent MissingOriginalPolicy = "use-current"
+	// MissingOriginalTwoWay calculates a two-way patch between the current and the modified object,
+	// which removes the fields missing from the modified object as well, except the ones populated by the server.
+	// The patch is usually the same as with MissingOriginalUseCurrent, but no original is reported.
+	MissingOriginalTwoWay MissingOriginalPolicy = "two-way"
+	// MissingOriginalFail makes Calculate return a MissingOriginalError.
+	MissingOriginalFail MissingOriginalPolicy = "fail"
+)
+
+// serverPopulatedFieldPaths are removed from the current object before it is used in place of the missing original,
+// so that the patch does not remove them, see MissingOriginalUseCurrent and MissingOriginalTwoWay.
+var serverPopulatedFieldPaths = mustParseFieldPaths([]string{
+	"metadata.uid",
+	"metadata.resourceVersion",
+	"metadata.creationTimestamp",
+	"metadata.generation",
+	"metadata.managedFields",
+	"metadata.selfLink",
+	"status",
+})
+
+// WithMissingOriginalPolicy sets what the patch maker does when the original configuration of an object is not known.
+// PatchResult.OriginalMissing reports such objects regardless of the policy. An empty policy stands for the default,
+// Calculate fails for unknown policies.
+func WithMissingOriginalPolicy(policy MissingOriginalPolicy) PatchMakerOption {
+	return func(p *PatchMaker) {
+		if policy == "" {
+			policy = MissingOriginalIgnoreAdditions
+		}
+		p.missingOriginalPolicy = policy
+	}
+}
+
+func (p MissingOriginalPolicy) validate() error {
+	switch p {
+	case MissingOriginalIgnoreAdditions, MissingOriginalUseCurrent, MissingOriginalTwoWay, MissingOriginalFail:
+		return nil
+	}
+	return errors.Errorf("unknown missing original policy %q", p)
+}
+
+// MissingOriginalError is returned for objects without an original configuration, see MissingOriginalFail.
+type MissingOriginalError struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+func (e *MissingOriginalError) Error() string {
+	if e.Namespace == "" {
+		return fmt.Sprintf("original configuration of %s %s is missing", e.Kind, e.Name)
+	}
+	return fmt.Sprintf("original configuration of %s %s/%s is missing", e.Kind, e.Namespace, e.Name)
+}
+
+func newMissingOriginalError(obj runtime.Object) error {
+	err := &MissingOriginalError{Kind: objectKind(obj)}
+	if accessor, accessorErr := meta.Accessor(obj); accessorErr == nil {
+		err.Namespace = accessor.GetNamespace()
+		err.Name = accessor.GetName()
+	}
+	return errors.WithStack(err)
+}
+
+// twoWayPatch calculates the patch between the current and the modified object, without an original.
+func (p *PatchMaker) twoWayPatch(currentObject runtime.Object, current, modified []byte) ([]byte, error) {
+	if patcher, schema, ok := p.schemaPatcher(currentObject); ok {
+		// Custom resources only accept merge patches, the strategic merge patch is only used to merge the lists.
+		patch, err := patcher.CreateTwoWayMergePatchUsingLookupPatchMeta(current, modified, schema)
+		if err != nil {
+			return nil, err
+		}
+		patchedCurrent, err := patcher.StrategicMergePatchUsingLookupPatchMeta(current, patch, schema)
+		if err != nil {
+			return nil, err
+		}
+		return p.jsonMergePatcher.CreateMergePatch(current, patchedCurrent)
+	}
+	if _, ok := currentObject.(*unstructured.Unstructured); ok {
+		return p.jsonMergePatcher.CreateMergePatch(current, modified)
+	}
+	return p.strategicMergePatcher.CreateTwoWayMergePatch(current, modified, currentObject)
+}
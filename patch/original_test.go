@@ -0,0 +1,146 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"emperror.dev/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestMissingOriginalPolicy(t *testing.T) {
+	current := newTestPod(
+		testContainer{Name: "app", Image: "app:1"},
+		testContainer{Name: "sidecar", Image: "sidecar:1"},
+	)
+	current.Spec.Hostname = "adopted"
+	modified := newTestPod(testContainer{Name: "app", Image: "app:2"})
+
+	tests := []struct {
+		policy    MissingOriginalPolicy
+		wantPaths []string
+	}{
+		{
+			policy:    "",
+			wantPaths: []string{"spec.containers[name=app].image"},
+		},
+		{
+			policy:    MissingOriginalIgnoreAdditions,
+			wantPaths: []string{"spec.containers[name=app].image"},
+		},
+		{
+			policy:    MissingOriginalUseCurrent,
+			wantPaths: []string{"spec.containers[name=app].image", "spec.containers[name=sidecar]", "spec.hostname"},
+		},
+		{
+			policy:    MissingOriginalTwoWay,
+			wantPaths: []string{"spec.containers[name=app].image", "spec.containers[name=sidecar]", "spec.hostname"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			patchMaker := NewPatchMaker(DefaultAnnotator, &K8sStrategicMergePatcher{}, &BaseJSONMergePatcher{}, WithMissingOriginalPolicy(tt.policy), WithChanges())
+			result, err := patchMaker.Calculate(current, modified)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !result.OriginalMissing {
+				t.Fatal("Expected the original to be reported missing")
+			}
+			var paths []string
+			for _, change := range result.Changes {
+				paths = append(paths, change.Path)
+			}
+			if !reflect.DeepEqual(paths, tt.wantPaths) {
+				t.Fatalf("Expected changes of %v, got %v", tt.wantPaths, result.Changes)
+			}
+		})
+	}
+
+	patchMaker := NewPatchMaker(DefaultAnnotator, &K8sStrategicMergePatcher{}, &BaseJSONMergePatcher{}, WithMissingOriginalPolicy(MissingOriginalFail))
+	_, err := patchMaker.Calculate(current, modified)
+	var missingOriginalError *MissingOriginalError
+	if !errors.As(err, &missingOriginalError) {
+		t.Fatalf("Expected a MissingOriginalError, got %v", err)
+	}
+	if missingOriginalError.Name != "test" || missingOriginalError.Namespace != "default" {
+		t.Fatalf("Unexpected error %+v", missingOriginalError)
+	}
+
+	patchMaker = NewPatchMaker(DefaultAnnotator, &K8sStrategicMergePatcher{}, &BaseJSONMergePatcher{}, WithMissingOriginalPolicy(""), WithTrace())
+	result, err := patchMaker.Calculate(current, modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if detail := result.Trace.Stages[2].Detail; detail != "missing, policy ignore-additions" {
+		t.Fatalf("Expected the default policy in the trace, got %q", detail)
+	}
+
+	patchMaker = NewPatchMaker(DefaultAnnotator, &K8sStrategicMergePatcher{}, &BaseJSONMergePatcher{}, WithMissingOriginalPolicy("ignore"))
+	if _, err := patchMaker.Calculate(current, modified); err == nil {
+		t.Fatal("Expected an error for an unknown policy")
+	}
+
+	patchMaker = NewPatchMaker(DefaultAnnotator, &K8sStrategicMergePatcher{}, &BaseJSONMergePatcher{}, WithMissingOriginalPolicy(MissingOriginalFail))
+
+	if err := DefaultAnnotator.SetLastAppliedAnnotation(current); err != nil {
+		t.Fatal(err)
+	}
+	result, err = patchMaker.Calculate(current, modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.OriginalMissing {
+		t.Fatal("Expected the original to be found")
+	}
+}
+
+func TestMissingOriginalPolicyKeepsServerPopulatedFields(t *testing.T) {
+	newConfigMap := func(value string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   map[string]interface{}{"name": "test", "namespace": "default"},
+			"data":       map[string]interface{}{"key": value},
+		}}
+	}
+	current := newConfigMap("value")
+	current.SetUID("0b6bd71f-5c4c-4e27-a2b2-2bb5e7d3e0a4")
+	current.SetResourceVersion("42")
+	current.SetGeneration(3)
+	unstructured.SetNestedField(current.Object, "2026-01-01T00:00:00Z", "metadata", "creationTimestamp")
+	unstructured.SetNestedField(current.Object, "Ready", "status", "phase")
+	current.Object["extra"] = "adopted"
+
+	for _, policy := range []MissingOriginalPolicy{MissingOriginalUseCurrent, MissingOriginalTwoWay} {
+		t.Run(string(policy), func(t *testing.T) {
+			patchMaker := NewPatchMaker(DefaultAnnotator, &K8sStrategicMergePatcher{}, &BaseJSONMergePatcher{}, WithMissingOriginalPolicy(policy))
+			result, err := patchMaker.Calculate(current, newConfigMap("new"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := `{"data":{"key":"new"},"extra":null}`
+			if string(result.Patch) != want {
+				t.Fatalf("Expected patch %s, got %s", want, result.Patch)
+			}
+			if strings.Contains(string(result.Original), "resourceVersion") {
+				t.Fatalf("Expected no server populated fields in the original, got %s", result.Original)
+			}
+		})
+	}
+}
@@ -15,12 +15,15 @@
 package patch
 
 import (
+	"context"
 	"fmt"
 
 	"emperror.dev/errors"
 	json "github.com/json-iterator/go"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
 )
 
 var DefaultPatchMaker = NewPatchMaker(DefaultAnnotator, &K8sStrategicMergePatcher{}, &BaseJSONMergePatcher{})
@@ -29,23 +32,74 @@ type Maker interface {
 	Calculate(currentObject, modifiedObject runtime.Object, opts ...CalculateOption) (*PatchResult, error)
 }
 
+// OriginalStore retrieves the last applied configuration of an object, or nil if it is not known.
+type OriginalStore interface {
+	GetOriginalConfiguration(obj runtime.Object) ([]byte, error)
+}
+
+// originalRestorer is implemented by stores that record a stripped down original configuration,
+// and need the current state of the object to restore the missing parts.
+type originalRestorer interface {
+	restoreOriginal(original, current []byte) ([]byte, error)
+}
+
 type PatchMaker struct {
-	annotator *Annotator
+	annotator     *Annotator
+	originalStore OriginalStore
 
 	strategicMergePatcher StrategicMergePatcher
 	jsonMergePatcher      JSONMergePatcher
+
+	configHashFastPath bool
+	redactor           *Redactor
+	sizeLimit          int
+	schemaRegistry     *SchemaRegistry
+	scheme             *runtime.Scheme
+
+	missingOriginalPolicy MissingOriginalPolicy
+	verification          bool
+	trace                 bool
+	changes               bool
+	immutableFields       *ImmutableFields
+}
+
+type PatchMakerOption func(*PatchMaker)
+
+func NewPatchMaker(annotator *Annotator, strategicMergePatcher StrategicMergePatcher, jsonMergePatcher JSONMergePatcher, opts ...PatchMakerOption) Maker {
+	return NewPatchMakerWithOriginalStore(annotator, strategicMergePatcher, jsonMergePatcher, opts...)
 }
 
-func NewPatchMaker(annotator *Annotator, strategicMergePatcher StrategicMergePatcher, jsonMergePatcher JSONMergePatcher) Maker {
-	return &PatchMaker{
-		annotator: annotator,
+// NewPatchMakerWithOriginalStore creates a patch maker that retrieves the original configuration
+// from the given store instead of the last applied annotation.
+func NewPatchMakerWithOriginalStore(originalStore OriginalStore, strategicMergePatcher StrategicMergePatcher, jsonMergePatcher JSONMergePatcher, opts ...PatchMakerOption) Maker {
+	annotator, _ := originalStore.(*Annotator)
+	p := &PatchMaker{
+		annotator:     annotator,
+		originalStore: originalStore,
 
 		strategicMergePatcher: strategicMergePatcher,
 		jsonMergePatcher:      jsonMergePatcher,
+
+		missingOriginalPolicy: MissingOriginalIgnoreAdditions,
+	}
+	for _, opt := range opts {
+		opt(p)
 	}
+	return p
 }
 
 func (p *PatchMaker) Calculate(currentObject, modifiedObject runtime.Object, opts ...CalculateOption) (*PatchResult, error) {
+	return p.CalculateContext(context.Background(), currentObject, modifiedObject, opts...)
+}
+
+// CalculateContext is like Calculate, but gives up as soon as the context is done.
+// The context is checked between the phases of the calculation, a single phase is not interrupted.
+func (p *PatchMaker) CalculateContext(ctx context.Context, currentObject, modifiedObject runtime.Object, opts ...CalculateOption) (*PatchResult, error) {
+	currentObject, modifiedObject, err := p.toTyped(currentObject, modifiedObject)
+	if err != nil {
+		return nil, err
+	}
+
 	current, err := json.ConfigCompatibleWithStandardLibrary.Marshal(currentObject)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to convert current object to byte sequence")
@@ -56,29 +110,213 @@ func (p *PatchMaker) Calculate(currentObject, modifiedObject runtime.Object, opt
 		return nil, errors.Wrap(err, "Failed to convert current object to byte sequence")
 	}
 
+	if result, err := p.fastPathResult(currentObject, current, modified); result != nil || err != nil {
+		return result, err
+	}
+
+	return p.calculate(ctx, currentObject, current, modified, opts...)
+}
+
+// fastPathResult returns the result of an up to date object if the config hash fast path is enabled,
+// otherwise nil, see WithConfigHashFastPath.
+func (p *PatchMaker) fastPathResult(currentObject runtime.Object, current, modified []byte) (*PatchResult, error) {
+	if !p.configHashFastPath || p.annotator == nil {
+		return nil, nil
+	}
+	upToDate, err := p.annotator.isUpToDate(currentObject, modified)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to compare config hashes")
+	}
+	if !upToDate {
+		return nil, nil
+	}
+	return p.upToDateResult(currentObject, current, modified)
+}
+
+func (p *PatchMaker) calculate(ctx context.Context, currentObject runtime.Object, current, modified []byte, opts ...CalculateOption) (*PatchResult, error) {
+	if err := p.missingOriginalPolicy.validate(); err != nil {
+		return nil, err
+	}
+	if err := p.checkSize("current", current); err != nil {
+		return nil, err
+	}
+	if err := p.checkSize("modified", modified); err != nil {
+		return nil, err
+	}
+
+	trace := p.newTrace()
+	trace.record(TraceStage{Name: TraceStageMarshal, Current: current, Modified: modified})
+
+	var err error
 	for _, opt := range opts {
+		if err := checkContext(ctx, "applying options"); err != nil {
+			return nil, err
+		}
 		current, modified, err = opt(current, modified)
 		if err != nil {
 			return nil, errors.Wrap(err, "Failed to apply option function")
 		}
+		trace.record(TraceStage{Name: TraceStageOption, Detail: optionName(opt), Current: current, Modified: modified})
+	}
+
+	if err := checkContext(ctx, "deleting nulls"); err != nil {
+		return nil, err
 	}
 
-	current, _, err = DeleteNullInJson(current)
+	current, err = deleteNullInJsonBytes(current)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to delete null from current object")
 	}
 
-	modified, _, err = DeleteNullInJson(modified)
+	modified, err = deleteNullInJsonBytes(modified)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to delete null from modified object")
 	}
+	trace.record(TraceStage{Name: TraceStageDeleteNulls, Current: current, Modified: modified})
 
-	original, err := p.annotator.GetOriginalConfiguration(currentObject)
+	original, err := p.originalStore.GetOriginalConfiguration(currentObject)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to get original configuration")
 	}
 
+	if restorer, ok := p.originalStore.(originalRestorer); ok {
+		original, err = restorer.restoreOriginal(original, current)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to restore omitted fields of the original configuration")
+		}
+	}
+
+	if err := p.checkSize("original", original); err != nil {
+		return nil, err
+	}
+
+	originalMissing := original == nil
+	if originalMissing {
+		switch p.missingOriginalPolicy {
+		case MissingOriginalFail:
+			return nil, newMissingOriginalError(currentObject)
+		case MissingOriginalUseCurrent:
+			original, err = deleteFields(current, serverPopulatedFieldPaths)
+			if err != nil {
+				return nil, errors.Wrap(err, "Failed to remove server populated fields from current object")
+			}
+		}
+	}
+	if originalMissing {
+		trace.record(TraceStage{Name: TraceStageOriginal, Detail: fmt.Sprintf("missing, policy %s", p.missingOriginalPolicy), Original: original})
+	} else {
+		trace.record(TraceStage{Name: TraceStageOriginal, Original: original})
+	}
+
 	var patch []byte
+	if originalMissing && p.missingOriginalPolicy == MissingOriginalTwoWay {
+		base, err := deleteFields(current, serverPopulatedFieldPaths)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to remove server populated fields from current object")
+		}
+		patch, err = p.twoWayPatch(currentObject, base, modified)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to generate two-way patch")
+		}
+		trace.record(TraceStage{Name: TraceStageTwoWayPatch, Patch: patch})
+	} else {
+		patch, err = p.threeWayPatch(ctx, currentObject, original, modified, current, trace)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := &PatchResult{
+		Patch:           patch,
+		Current:         current,
+		Modified:        modified,
+		Original:        original,
+		OriginalMissing: originalMissing,
+		Trace:           trace,
+
+		kind:      objectKind(currentObject),
+		redactor:  p.redactor,
+		patchType: patchType(currentObject),
+	}
+
+	if !result.IsEmpty() {
+		// Applying the patch is only worth it if the patched object is needed right away,
+		// JSONPatch and Diff apply it on demand otherwise.
+		if p.changes || p.verification || trace != nil {
+			if err := checkContext(ctx, "applying the patch"); err != nil {
+				return nil, err
+			}
+			result.patched, err = p.applyPatch(currentObject, current, patch)
+			if err != nil {
+				return nil, errors.Wrap(err, "Failed to apply patch")
+			}
+		} else {
+			result.applyPatch = func() ([]byte, error) {
+				return p.applyPatch(currentObject, current, patch)
+			}
+		}
+	}
+	if !result.IsEmpty() && p.changes {
+		if err := checkContext(ctx, "listing the changes"); err != nil {
+			return nil, err
+		}
+		result.Changes, err = calculateChanges(current, result.patched, original, modified, p.lookupPatchMeta(currentObject), result.getRedactor(), result.kind)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to list the changes")
+		}
+		p.setImmutableFieldChanges(result, currentObject)
+	}
+	trace.record(TraceStage{Name: TraceStageFinalPatch, Patch: patch, Patched: result.patched})
+
+	if p.verification {
+		if err := checkContext(ctx, "verifying the result"); err != nil {
+			return nil, err
+		}
+		patched := current
+		if !result.IsEmpty() {
+			patched = result.patched
+		}
+		if err := verifyPatched(patched, modified, p.lookupPatchMeta(currentObject)); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// upToDateResult returns the empty patch of an object found up to date by the config hash fast path.
+// The objects are not processed by the options, and the original configuration is not restored.
+func (p *PatchMaker) upToDateResult(currentObject runtime.Object, current, modified []byte) (*PatchResult, error) {
+	original, err := p.originalStore.GetOriginalConfiguration(currentObject)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get original configuration")
+	}
+
+	result := &PatchResult{
+		Patch:           []byte("{}"),
+		Current:         current,
+		Modified:        modified,
+		Original:        original,
+		OriginalMissing: original == nil,
+		Trace:           p.newTrace(),
+
+		kind:      objectKind(currentObject),
+		redactor:  p.redactor,
+		patchType: patchType(currentObject),
+	}
+	result.Trace.record(TraceStage{Name: TraceStageConfigHash, Current: current, Modified: modified, Original: original, Patch: result.Patch})
+	return result, nil
+}
+
+// threeWayPatch calculates the patch between the current and the modified object, based on the original.
+// The patches are recorded in the trace, which can be nil.
+func (p *PatchMaker) threeWayPatch(ctx context.Context, currentObject runtime.Object, original, modified, current []byte, trace *Trace) ([]byte, error) {
+	if err := checkContext(ctx, "calculating the three-way merge patch"); err != nil {
+		return nil, err
+	}
+
+	var patch []byte
+	var err error
 
 	switch currentObject.(type) {
 	default:
@@ -86,9 +324,13 @@ func (p *PatchMaker) Calculate(currentObject, modifiedObject runtime.Object, opt
 		if err != nil {
 			return nil, errors.Wrap(err, "Failed to generate strategic merge patch")
 		}
+		trace.record(TraceStage{Name: TraceStageThreeWayPatch, Patch: patch})
 		// $setElementOrder can make it hard to decide whether there is an actual diff or not.
 		// In cases like that trying to apply the patch locally on current will make it clear.
 		if string(patch) != "{}" {
+			if err := checkContext(ctx, "verifying the patch"); err != nil {
+				return nil, err
+			}
 			patchCurrent, err := p.strategicMergePatcher.StrategicMergePatch(current, patch, currentObject)
 			if err != nil {
 				return nil, errors.Wrap(err, "Failed to apply patch again to check for an actual diff")
@@ -97,27 +339,73 @@ func (p *PatchMaker) Calculate(currentObject, modifiedObject runtime.Object, opt
 			if err != nil {
 				return nil, errors.Wrap(err, "Failed to create patch again to check for an actual diff")
 			}
+			trace.record(TraceStage{Name: TraceStageVerificationPatch, Patch: patch, Patched: patchCurrent})
 		}
 	case *unstructured.Unstructured:
-		patch, err = p.unstructuredJsonMergePatch(original, modified, current)
+		if patcher, schema, ok := p.schemaPatcher(currentObject); ok {
+			patch, err = p.unstructuredStrategicMergePatch(original, modified, current, patcher, schema, trace)
+			if err != nil {
+				return nil, errors.Wrap(err, "Failed to generate strategic merge patch from schema")
+			}
+			break
+		}
+		patch, err = p.unstructuredJsonMergePatch(original, modified, current, trace)
 		if err != nil {
 			return nil, errors.Wrap(err, "Failed to generate merge patch")
 		}
 	}
 
-	return &PatchResult{
-		Patch:    patch,
-		Current:  current,
-		Modified: modified,
-		Original: original,
-	}, nil
+	return patch, nil
 }
 
-func (p *PatchMaker) unstructuredJsonMergePatch(original, modified, current []byte) ([]byte, error) {
+// applyPatch applies a patch calculated for the object to current, the same way the API server would.
+func (p *PatchMaker) applyPatch(currentObject runtime.Object, current, patch []byte) ([]byte, error) {
+	if _, ok := currentObject.(*unstructured.Unstructured); ok {
+		return p.jsonMergePatcher.MergePatch(current, patch)
+	}
+	return p.strategicMergePatcher.StrategicMergePatch(current, patch, currentObject)
+}
+
+// schemaPatcher returns the patcher and the schema to calculate a strategic merge patch for an unstructured object,
+// if the kind of the object is registered in the schema registry of the patch maker.
+func (p *PatchMaker) schemaPatcher(obj runtime.Object) (LookupPatchMetaStrategicMergePatcher, strategicpatch.LookupPatchMeta, bool) {
+	if _, ok := obj.(*unstructured.Unstructured); !ok || p.schemaRegistry == nil {
+		return nil, nil, false
+	}
+	patcher, ok := p.strategicMergePatcher.(LookupPatchMetaStrategicMergePatcher)
+	if !ok {
+		return nil, nil, false
+	}
+	schema, ok := p.schemaRegistry.LookupPatchMeta(obj.GetObjectKind().GroupVersionKind())
+	return patcher, schema, ok
+}
+
+// lookupPatchMeta is like the lookupPatchMeta function, but takes the schemas registered for unstructured objects into account.
+func (p *PatchMaker) lookupPatchMeta(obj runtime.Object) strategicpatch.LookupPatchMeta {
+	if _, schema, ok := p.schemaPatcher(obj); ok {
+		return schema
+	}
+	return lookupPatchMeta(obj)
+}
+
+// lookupPatchMeta returns the strategic merge patch metadata of the object, or nil if it has none.
+func lookupPatchMeta(obj runtime.Object) strategicpatch.LookupPatchMeta {
+	if _, ok := obj.(*unstructured.Unstructured); ok {
+		return nil
+	}
+	lookupPatchMeta, err := strategicpatch.NewPatchMetaFromStruct(obj)
+	if err != nil {
+		return nil
+	}
+	return lookupPatchMeta
+}
+
+func (p *PatchMaker) unstructuredJsonMergePatch(original, modified, current []byte, trace *Trace) ([]byte, error) {
 	patch, err := p.jsonMergePatcher.CreateThreeWayJSONMergePatch(original, modified, current)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to generate merge patch")
 	}
+	trace.record(TraceStage{Name: TraceStageThreeWayPatch, Patch: patch})
 	// Apply the patch to the current object and create a merge patch to see if there has any effective changes been made
 	if string(patch) != "{}" {
 		// apply the patch
@@ -130,21 +418,83 @@ func (p *PatchMaker) unstructuredJsonMergePatch(original, modified, current []by
 		if err != nil {
 			return nil, errors.Wrap(err, "Failed to create patch between the current and patched current object")
 		}
+		trace.record(TraceStage{Name: TraceStageVerificationPatch, Patch: patch, Patched: patchedCurrent})
 	}
 	return patch, err
 }
 
+// unstructuredStrategicMergePatch is like unstructuredJsonMergePatch, but merges lists based on the schema of the object.
+// The resulting patch is a JSON merge patch without strategic merge patch directives, as custom resources do not accept
+// strategic merge patches: lists changed by the strategic merge patch are replaced as a whole.
+func (p *PatchMaker) unstructuredStrategicMergePatch(original, modified, current []byte, patcher LookupPatchMetaStrategicMergePatcher, schema strategicpatch.LookupPatchMeta, trace *Trace) ([]byte, error) {
+	patch, err := patcher.CreateThreeWayMergePatchUsingLookupPatchMeta(original, modified, current, schema)
+	if err != nil {
+		return nil, err
+	}
+	trace.record(TraceStage{Name: TraceStageThreeWayPatch, Patch: patch})
+	// Same as for typed objects, applying the patch locally tells whether there is an actual diff,
+	// and gives the object to calculate the merge patch from.
+	if string(patch) != "{}" {
+		patchedCurrent, err := patcher.StrategicMergePatchUsingLookupPatchMeta(current, patch, schema)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to apply patch again to check for an actual diff")
+		}
+		patch, err = p.jsonMergePatcher.CreateMergePatch(current, patchedCurrent)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to create merge patch between the current and patched current object")
+		}
+		trace.record(TraceStage{Name: TraceStageVerificationPatch, Patch: patch, Patched: patchedCurrent})
+	}
+
+	return patch, nil
+}
+
 type PatchResult struct {
 	Patch    []byte
 	Current  []byte
 	Modified []byte
 	Original []byte
+
+	// Changes lists the field level changes the patch makes to the current object, see WithChanges.
+	Changes []Change
+	// Conflicts lists the changed fields owned by other field managers, see ServerSideApplyMaker.
+	Conflicts []FieldConflict
+	// OriginalMissing tells that the object has no original configuration, see WithMissingOriginalPolicy.
+	OriginalMissing bool
+	// ImmutableFieldChanges lists the changes to immutable fields, see WithChanges and WithImmutableFields.
+	ImmutableFieldChanges []Change
+	// Recreate tells how the object has to be recreated if the patch changes immutable fields, see RequiresRecreate.
+	Recreate RecreateStrategy
+	// UnrecoverablePaths lists the hashed fields Rollback can not restore, as only their hash was recorded.
+	UnrecoverablePaths []string
+	// Trace records the objects after each stage of the calculation, see WithTrace.
+	Trace *Trace
+
+	// patched is the current object with the patch applied, if it was needed during the calculation,
+	// otherwise applyPatch returns it, see getPatched.
+	patched    []byte
+	applyPatch func() ([]byte, error)
+	// patchType is the type of Patch, see PatchType.
+	patchType types.PatchType
+	// kind of the object, used to find the redaction rules that apply.
+	kind     string
+	redactor *Redactor
+}
+
+// getPatched returns the current object with the patch applied.
+func (p *PatchResult) getPatched() ([]byte, error) {
+	if p.patched == nil && p.applyPatch != nil {
+		return p.applyPatch()
+	}
+	return p.patched, nil
 }
 
 func (p *PatchResult) IsEmpty() bool {
 	return string(p.Patch) == "{}"
 }
 
+// String formats the result with its sensitive values redacted, see WithRedactor.
 func (p *PatchResult) String() string {
-	return fmt.Sprintf("\nPatch: %s \nCurrent: %s\nModified: %s\nOriginal: %s\n", p.Patch, p.Current, p.Modified, p.Original)
+	return fmt.Sprintf("\nPatch: %s \nCurrent: %s\nModified: %s\nOriginal: %s\n",
+		p.redacted(p.Patch), p.redacted(p.Current), p.redacted(p.Modified), p.redacted(p.Original))
 }
@@ -28,6 +28,14 @@ type StrategicMergePatcher interface {
 	CreateThreeWayMergePatch(original, modified, current []byte, dataStruct interface{}) ([]byte, error)
 }
 
+// LookupPatchMetaStrategicMergePatcher is implemented by strategic merge patchers that can take
+// the patch metadata from a schema, instead of the tags of a Go struct, see SchemaRegistry.
+type LookupPatchMetaStrategicMergePatcher interface {
+	StrategicMergePatchUsingLookupPatchMeta(original, patch []byte, schema strategicpatch.LookupPatchMeta) ([]byte, error)
+	CreateTwoWayMergePatchUsingLookupPatchMeta(original, modified []byte, schema strategicpatch.LookupPatchMeta) ([]byte, error)
+	CreateThreeWayMergePatchUsingLookupPatchMeta(original, modified, current []byte, schema strategicpatch.LookupPatchMeta) ([]byte, error)
+}
+
 type JSONMergePatcher interface {
 	MergePatch(docData, patchData []byte) ([]byte, error)
 	CreateMergePatch(originalJSON, modifiedJSON []byte) ([]byte, error)
@@ -55,6 +63,18 @@ func (p *K8sStrategicMergePatcher) CreateThreeWayMergePatch(original, modified,
 	return strategicpatch.CreateThreeWayMergePatch(original, modified, current, lookupPatchMeta, true, p.PreconditionFuncs...)
 }
 
+func (p *K8sStrategicMergePatcher) StrategicMergePatchUsingLookupPatchMeta(original, patch []byte, schema strategicpatch.LookupPatchMeta) ([]byte, error) {
+	return strategicpatch.StrategicMergePatchUsingLookupPatchMeta(original, patch, schema)
+}
+
+func (p *K8sStrategicMergePatcher) CreateTwoWayMergePatchUsingLookupPatchMeta(original, modified []byte, schema strategicpatch.LookupPatchMeta) ([]byte, error) {
+	return strategicpatch.CreateTwoWayMergePatchUsingLookupPatchMeta(original, modified, schema, p.PreconditionFuncs...)
+}
+
+func (p *K8sStrategicMergePatcher) CreateThreeWayMergePatchUsingLookupPatchMeta(original, modified, current []byte, schema strategicpatch.LookupPatchMeta) ([]byte, error) {
+	return strategicpatch.CreateThreeWayMergePatch(original, modified, current, schema, true, p.PreconditionFuncs...)
+}
+
 type BaseJSONMergePatcher struct{}
 
 func (p *BaseJSONMergePatcher) MergePatch(docData, patchData []byte) ([]byte, error) {
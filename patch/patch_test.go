@@ -19,8 +19,46 @@ import (
 	"testing"
 
 	json "github.com/json-iterator/go"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// testPod is a minimal typed object with strategic merge patch metadata.
+type testPod struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec testPodSpec `json:"spec,omitempty"`
+}
+
+type testPodSpec struct {
+	Containers []testContainer `json:"containers,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
+	Hostname   string          `json:"hostname,omitempty"`
+}
+
+type testContainer struct {
+	Name  string   `json:"name"`
+	Image string   `json:"image,omitempty"`
+	Args  []string `json:"args,omitempty"`
+	Port  int32    `json:"port,omitempty"`
+}
+
+func (p *testPod) DeepCopyObject() runtime.Object {
+	c := &testPod{}
+	if err := json.Unmarshal(mustMarshal(p), c); err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func newTestPod(containers ...testContainer) *testPod {
+	return &testPod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec:       testPodSpec{Containers: containers},
+	}
+}
+
 func Test_unstructuredJsonMergePatch(t *testing.T) {
 	type args struct {
 		original map[string]interface{}
@@ -95,7 +133,7 @@ func Test_unstructuredJsonMergePatch(t *testing.T) {
 			got, err := DefaultPatchMaker.(*PatchMaker).unstructuredJsonMergePatch(
 				mustFromUnstructured(tt.args.original),
 				mustFromUnstructured(tt.args.modified),
-				mustFromUnstructured(tt.args.current))
+				mustFromUnstructured(tt.args.current), nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("unstructuredJsonMergePatch() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -115,6 +153,14 @@ func mustFromUnstructured(u map[string]interface{}) []byte {
 	return r
 }
 
+func mustMarshal(obj interface{}) []byte {
+	r, err := json.ConfigCompatibleWithStandardLibrary.Marshal(obj)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
 func mustToUnstructured(data []byte) map[string]interface{} {
 	m := make(map[string]interface{})
 	if err := json.Unmarshal(data, &m); err != nil {
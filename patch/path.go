@@ -0,0 +1,180 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"fmt"
+	"strings"
+
+	"emperror.dev/errors"
+	json "github.com/json-iterator/go"
+)
+
+// jsonWithNumbers decodes numbers as json.Number instead of float64, like structured-merge-diff does,
+// so that integers above 2^53 keep their precision when a document is decoded into a map and encoded again.
+var jsonWithNumbers = json.Config{
+	EscapeHTML:             true,
+	SortMapKeys:            true,
+	ValidateJsonRawMessage: true,
+	UseNumber:              true,
+}.Froze()
+
+func unmarshalWithNumbers(data []byte, v interface{}) error {
+	return jsonWithNumbers.Unmarshal(data, v)
+}
+
+// fieldPath is a parsed, dot separated path of an object field, like
+// "spec.versions[].schema" where "[]" selects every element of a list.
+// The last segment of a path always selects a single field.
+type fieldPath []pathSegment
+
+type pathSegment struct {
+	key  string
+	each bool
+}
+
+func parseFieldPath(path string) (fieldPath, error) {
+	if path == "" {
+		return nil, errors.New("empty field path")
+	}
+	var p fieldPath
+	parts := strings.Split(path, ".")
+	for i, part := range parts {
+		each := strings.HasSuffix(part, "[]")
+		key := strings.TrimSuffix(part, "[]")
+		if key == "" || strings.ContainsAny(key, "[]") || (each && i == len(parts)-1) {
+			return nil, errors.Errorf("invalid field path %q", path)
+		}
+		p = append(p, pathSegment{key: key, each: each})
+	}
+	return p, nil
+}
+
+func mustParseFieldPaths(paths []string) []fieldPath {
+	parsed := make([]fieldPath, 0, len(paths))
+	for _, path := range paths {
+		p, err := parseFieldPath(path)
+		if err != nil {
+			panic(err)
+		}
+		parsed = append(parsed, p)
+	}
+	return parsed
+}
+
+func (p fieldPath) String() string {
+	parts := make([]string, len(p))
+	for i, s := range p {
+		parts[i] = s.key
+		if s.each {
+			parts[i] += "[]"
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// visit calls fn with the parent map and the key of every field matching the path.
+// Fields are visited even if the last key is missing from the parent map.
+func (p fieldPath) visit(obj map[string]interface{}, fn func(parent map[string]interface{}, key string)) {
+	p.visitWithLocation(obj, "", func(parent map[string]interface{}, key, _ string) {
+		fn(parent, key)
+	})
+}
+
+// visitWithLocation is like visit, but also passes the location of the field,
+// which is the path with list elements identified by their index, e.g. "spec.versions[0].schema".
+func (p fieldPath) visitWithLocation(obj map[string]interface{}, location string, fn func(parent map[string]interface{}, key, location string)) {
+	if len(p) == 0 || obj == nil {
+		return
+	}
+	s := p[0]
+	location = joinChangePath(location, s.key)
+	if len(p) == 1 {
+		fn(obj, s.key, location)
+		return
+	}
+	value, ok := obj[s.key]
+	if !ok {
+		return
+	}
+	if !s.each {
+		if m, ok := value.(map[string]interface{}); ok {
+			p[1:].visitWithLocation(m, location, fn)
+		}
+		return
+	}
+	list, ok := value.([]interface{})
+	if !ok {
+		return
+	}
+	for i, item := range list {
+		if m, ok := item.(map[string]interface{}); ok {
+			p[1:].visitWithLocation(m, fmt.Sprintf("%s[%d]", location, i), fn)
+		}
+	}
+}
+
+// delete removes every field matching the path from the object.
+func (p fieldPath) delete(obj map[string]interface{}) {
+	p.visit(obj, func(parent map[string]interface{}, key string) {
+		delete(parent, key)
+	})
+}
+
+// restore walks the original and the current object side by side and calls fn
+// for every field matching the path in the original. The current parent map is nil
+// if it has no counterpart. List elements are paired by their "name" field if they
+// have one, otherwise by their index.
+func (p fieldPath) restore(original, current map[string]interface{}, fn func(original, current map[string]interface{}, key string)) {
+	if len(p) == 0 || original == nil {
+		return
+	}
+	s := p[0]
+	if len(p) == 1 {
+		fn(original, current, s.key)
+		return
+	}
+	if !s.each {
+		o, _ := original[s.key].(map[string]interface{})
+		c, _ := current[s.key].(map[string]interface{})
+		p[1:].restore(o, c, fn)
+		return
+	}
+	originalList, _ := original[s.key].([]interface{})
+	currentList, _ := current[s.key].([]interface{})
+	for i, item := range originalList {
+		o, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		p[1:].restore(o, matchListElement(o, i, currentList), fn)
+	}
+}
+
+func matchListElement(element map[string]interface{}, index int, list []interface{}) map[string]interface{} {
+	if name, ok := element["name"]; ok {
+		for _, item := range list {
+			if m, ok := item.(map[string]interface{}); ok && m["name"] == name {
+				return m
+			}
+		}
+		return nil
+	}
+	if index < len(list) {
+		m, _ := list[index].(map[string]interface{})
+		return m
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+
+	"emperror.dev/errors"
+	json "github.com/json-iterator/go"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	redactedValue        = "<redacted>"
+	redactedChangedValue = "<redacted, changed>"
+	redactionFailed      = "<redaction failed>"
+
+	// KubectlLastAppliedConfig is the annotation kubectl apply records the last applied configuration in.
+	KubectlLastAppliedConfig = "kubectl.kubernetes.io/last-applied-configuration"
+)
+
+// DefaultRedactor hides the values of Secrets.
+var DefaultRedactor = NewRedactor(RedactKindPaths("Secret", "data", "stringData"))
+
+// Redactor hides sensitive values when a PatchResult is formatted.
+type Redactor struct {
+	paths           []fieldPath
+	kindPaths       map[string][]fieldPath
+	envNamePatterns []*regexp.Regexp
+	// annotations hold a copy of the whole object, they are hidden whenever a rule applies to the object.
+	annotations []string
+}
+
+type RedactorOption func(*Redactor)
+
+// NewRedactor creates a redactor with the given rules. Without rules nothing is redacted.
+// The last applied configuration annotations of the objects the rules apply to are redacted as well,
+// as they contain the sensitive values too, see RedactAnnotations.
+func NewRedactor(opts ...RedactorOption) *Redactor {
+	r := &Redactor{
+		kindPaths:   map[string][]fieldPath{},
+		annotations: []string{LastAppliedConfig, LastAppliedConfig + HistorySuffix, KubectlLastAppliedConfig},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// RedactPaths hides the value of the given fields of every object. If a field is a map,
+// only the values of its entries are hidden. It panics if a path is invalid.
+func RedactPaths(paths ...string) RedactorOption {
+	return func(r *Redactor) {
+		r.paths = append(r.paths, mustParseFieldPaths(paths)...)
+	}
+}
+
+// RedactKindPaths is like RedactPaths, but only applies to objects of the given kind.
+func RedactKindPaths(kind string, paths ...string) RedactorOption {
+	return func(r *Redactor) {
+		r.kindPaths[kind] = append(r.kindPaths[kind], mustParseFieldPaths(paths)...)
+	}
+}
+
+// RedactAnnotations hides the given annotations of the objects any other rule applies to,
+// like the last applied configuration annotation of an Annotator with a custom key.
+func RedactAnnotations(keys ...string) RedactorOption {
+	return func(r *Redactor) {
+		r.annotations = append(r.annotations, keys...)
+	}
+}
+
+// RedactEnvValues hides the value of container environment variables with a name matching
+// any of the regular expressions. It panics if an expression is invalid.
+func RedactEnvValues(namePatterns ...string) RedactorOption {
+	return func(r *Redactor) {
+		for _, pattern := range namePatterns {
+			r.envNamePatterns = append(r.envNamePatterns, regexp.MustCompile(pattern))
+		}
+	}
+}
+
+// WithRedactor sets the redactor used when the results of the patch maker are formatted.
+// DefaultRedactor is used if not set, NewRedactor() without rules turns redaction off.
+func WithRedactor(redactor *Redactor) PatchMakerOption {
+	return func(p *PatchMaker) {
+		p.redactor = redactor
+	}
+}
+
+// redact replaces the sensitive values of the JSON document with the value returned by replace,
+// which gets the location of the value, like "data.password" or "spec.containers[0].env[1].value".
+func (r *Redactor) redact(data []byte, kind string, replace func(location string, value interface{}) interface{}) ([]byte, error) {
+	if len(data) == 0 || (len(r.paths) == 0 && len(r.kindPaths[kind]) == 0 && len(r.envNamePatterns) == 0) {
+		return data, nil
+	}
+
+	var obj map[string]interface{}
+	if err := unmarshalWithNumbers(data, &obj); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal document to redact")
+	}
+
+	redactField := func(parent map[string]interface{}, key, location string) {
+		value, ok := parent[key]
+		if !ok {
+			return
+		}
+		if entries, ok := value.(map[string]interface{}); ok {
+			for k, v := range entries {
+				entries[k] = replace(location+"."+k, v)
+			}
+			return
+		}
+		parent[key] = replace(location, value)
+	}
+	for _, p := range r.paths {
+		p.visitWithLocation(obj, "", redactField)
+	}
+	for _, p := range r.kindPaths[kind] {
+		p.visitWithLocation(obj, "", redactField)
+	}
+	if len(r.envNamePatterns) > 0 {
+		r.redactEnv(obj, "", replace)
+	}
+	if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+		if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+			for _, key := range r.annotations {
+				if value, ok := annotations[key]; ok {
+					annotations[key] = replace("metadata.annotations."+key, value)
+				}
+			}
+		}
+	}
+
+	return json.ConfigCompatibleWithStandardLibrary.Marshal(obj)
+}
+
+func (r *Redactor) redactEnv(value interface{}, location string, replace func(location string, value interface{}) interface{}) {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		for key, child := range typedValue {
+			childLocation := joinChangePath(location, key)
+			if env, ok := child.([]interface{}); ok && key == "env" {
+				for i, item := range env {
+					variable, ok := item.(map[string]interface{})
+					if !ok {
+						continue
+					}
+					name, _ := variable["name"].(string)
+					if _, ok := variable["value"]; ok && r.matchesEnvName(name) {
+						variable["value"] = replace(fmt.Sprintf("%s[%d].value", childLocation, i), variable["value"])
+					}
+				}
+				continue
+			}
+			r.redactEnv(child, childLocation, replace)
+		}
+	case []interface{}:
+		for i, item := range typedValue {
+			r.redactEnv(item, fmt.Sprintf("%s[%d]", location, i), replace)
+		}
+	}
+}
+
+func (r *Redactor) matchesEnvName(name string) bool {
+	for _, pattern := range r.envNamePatterns {
+		if pattern.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactAll(string, interface{}) interface{} {
+	return redactedValue
+}
+
+// redactPair redacts two versions of the same document, marking the values that differ between them.
+func (r *Redactor) redactPair(old, new []byte, kind string) ([]byte, []byte, error) {
+	oldValues := map[string]interface{}{}
+	redactedOld, err := r.redact(old, kind, func(location string, value interface{}) interface{} {
+		oldValues[location] = value
+		return redactedValue
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	redactedNew, err := r.redact(new, kind, func(location string, value interface{}) interface{} {
+		if oldValue, ok := oldValues[location]; ok && reflect.DeepEqual(oldValue, value) {
+			return redactedValue
+		}
+		return redactedChangedValue
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return redactedOld, redactedNew, nil
+}
+
+func (p *PatchResult) getRedactor() *Redactor {
+	if p.redactor == nil {
+		return DefaultRedactor
+	}
+	return p.redactor
+}
+
+// redacted returns the document with its sensitive values hidden,
+// or a placeholder if it can not be parsed, to avoid leaking anything.
+func (p *PatchResult) redacted(data []byte) []byte {
+	redacted, err := p.getRedactor().redact(data, p.kind, redactAll)
+	if err != nil {
+		return []byte(redactionFailed)
+	}
+	return redacted
+}
+
+// objectKind returns the kind of the object, falling back to the name of
+// its Go type, as typed objects returned by clients usually have no kind set.
+func objectKind(obj runtime.Object) string {
+	if kind := obj.GetObjectKind().GroupVersionKind().Kind; kind != "" {
+		return kind
+	}
+	t := reflect.TypeOf(obj)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
+}
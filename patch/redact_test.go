@@ -0,0 +1,154 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"emperror.dev/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newTestSecret(password string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata":   map[string]interface{}{"name": "test"},
+		"data":       map[string]interface{}{"password": password, "username": "YWRtaW4="},
+	}}
+}
+
+func TestSecretsAreRedacted(t *testing.T) {
+	current := newTestSecret("c2VjcmV0MQ==")
+	if err := DefaultAnnotator.SetLastAppliedAnnotation(current); err != nil {
+		t.Fatal(err)
+	}
+	modified := newTestSecret("c2VjcmV0Mg==")
+	if err := DefaultAnnotator.SetLastAppliedAnnotation(modified); err != nil {
+		t.Fatal(err)
+	}
+	result, err := changesPatchMaker.Calculate(current, modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	formatted := []string{
+		result.String(),
+		fmt.Sprintf("%v", errors.WithDetails(errors.New("Objects did not match"), "patch", result)),
+		fmt.Sprintf("%v", result.Changes),
+	}
+	for _, change := range result.Changes {
+		formatted = append(formatted, change.String())
+	}
+	operations, err := result.JSONPatch(WithRedactedValues())
+	if err != nil {
+		t.Fatal(err)
+	}
+	formatted = append(formatted, string(mustMarshal(operations)))
+	diff, err := result.Diff()
+	if err != nil {
+		t.Fatal(err)
+	}
+	formatted = append(formatted, diff)
+	for _, s := range formatted {
+		if strings.Contains(s, "c2VjcmV0") || strings.Contains(s, "YWRtaW4=") {
+			t.Fatalf("Expected secret values to be redacted, got %s", s)
+		}
+	}
+
+	// The last applied annotations hold the whole object, zipped and encoded.
+	for _, data := range [][]byte{result.Current, result.Patch} {
+		redacted := &unstructured.Unstructured{Object: mustToUnstructured(result.redacted(data))}
+		if value := redacted.GetAnnotations()[LastAppliedConfig]; value != redactedValue {
+			t.Fatalf("Expected the last applied annotation to be redacted, got %q", value)
+		}
+		if original, err := DefaultAnnotator.GetOriginalConfiguration(redacted); err == nil && strings.Contains(string(original), "c2VjcmV0") {
+			t.Fatalf("Expected the last applied annotation not to leak secret values, got %s", original)
+		}
+	}
+
+	var passwordChanged bool
+	for _, change := range result.Changes {
+		if change.Path == "data.password" {
+			passwordChanged = change.Old == redactedValue && change.New == redactedChangedValue
+		}
+	}
+	if !passwordChanged {
+		t.Fatalf("Expected a redacted change of the password, got %v", result.Changes)
+	}
+
+	// The JSON Patch is sent to the API server, so its values are real by default.
+	operations, err = result.JSONPatch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(mustMarshal(operations)), "c2VjcmV0Mg==") {
+		t.Fatalf("Expected unredacted JSON Patch values, got %v", operations)
+	}
+	for _, operation := range operations {
+		if value, ok := operation.Value.(string); ok && strings.HasPrefix(value, "<redacted") {
+			t.Fatalf("Expected unredacted JSON Patch values, got %v", operations)
+		}
+	}
+
+	if !strings.Contains(diff, "-  password: <redacted>\n+  password: <redacted, changed>\n") {
+		t.Fatalf("Expected the diff to show the changed secret value, got\n%s", diff)
+	}
+	if !strings.Contains(diff, " username: <redacted>\n") {
+		t.Fatalf("Expected the diff to keep the unchanged secret value as context, got\n%s", diff)
+	}
+
+	result.redactor = NewRedactor()
+	if !strings.Contains(result.String(), "c2VjcmV0Mg==") {
+		t.Fatalf("Expected no redaction without rules, got %s", result.String())
+	}
+}
+
+func TestEnvValuesAreRedacted(t *testing.T) {
+	patchMaker := NewPatchMaker(DefaultAnnotator, &K8sStrategicMergePatcher{}, &BaseJSONMergePatcher{},
+		WithRedactor(NewRedactor(RedactEnvValues("(?i)password"), RedactPaths("spec.hostname"))), WithChanges())
+
+	newObject := func(password string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Pod",
+			"metadata":   map[string]interface{}{"name": "test"},
+			"spec": map[string]interface{}{
+				"hostname": "internal.example.com",
+				"containers": []interface{}{map[string]interface{}{
+					"name": "app",
+					"env": []interface{}{
+						map[string]interface{}{"name": "DB_PASSWORD", "value": password},
+						map[string]interface{}{"name": "DB_USER", "value": "admin"},
+					},
+				}},
+			},
+		}}
+	}
+
+	result, err := patchMaker.Calculate(newObject("secret1"), newObject("secret2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := result.String()
+	if strings.Contains(s, "secret") || strings.Contains(s, "internal.example.com") {
+		t.Fatalf("Expected env value and hostname to be redacted, got %s", s)
+	}
+	if !strings.Contains(s, "admin") {
+		t.Fatalf("Expected other env values to be kept, got %s", s)
+	}
+}
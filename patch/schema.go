@@ -0,0 +1,281 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"strings"
+	"sync"
+
+	"emperror.dev/errors"
+	json "github.com/json-iterator/go"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/kube-openapi/pkg/util/proto"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	patchStrategyExtension = "x-kubernetes-patch-strategy"
+	patchMergeKeyExtension = "x-kubernetes-patch-merge-key"
+	listTypeExtension      = "x-kubernetes-list-type"
+	listMapKeysExtension   = "x-kubernetes-list-map-keys"
+	gvkExtension           = "x-kubernetes-group-version-kind"
+)
+
+// SchemaRegistry holds the schemas of kinds that have no Go type, like custom resources.
+// Unstructured objects of a registered kind are compared with a strategic merge patch,
+// where the elements of lists are merged by their keys, instead of a JSON merge patch
+// that replaces lists as a whole. The result is still turned into a JSON merge patch,
+// as custom resources do not accept strategic merge patches.
+//
+// Lists are merged by key if their schema has the x-kubernetes-patch-merge-key extension,
+// or x-kubernetes-list-type is map with a single x-kubernetes-list-map-keys entry.
+// Lists with x-kubernetes-list-type set are merged as sets. Other lists are replaced.
+type SchemaRegistry struct {
+	mu      sync.RWMutex
+	schemas map[schema.GroupVersionKind]strategicpatch.LookupPatchMeta
+}
+
+func NewSchemaRegistry() *SchemaRegistry {
+	return &SchemaRegistry{
+		schemas: map[schema.GroupVersionKind]strategicpatch.LookupPatchMeta{},
+	}
+}
+
+// WithSchemaRegistry makes the patch maker look up the schemas of unstructured objects in the registry.
+// The patch maker falls back to a JSON merge patch for objects of unknown kinds, and if its strategic
+// merge patcher does not implement LookupPatchMetaStrategicMergePatcher.
+func WithSchemaRegistry(registry *SchemaRegistry) PatchMakerOption {
+	return func(p *PatchMaker) {
+		p.schemaRegistry = registry
+	}
+}
+
+// AddStructuralSchema registers the structural schema of a kind, like the openAPIV3Schema
+// of a CustomResourceDefinition version, in JSON or YAML format.
+func (r *SchemaRegistry) AddStructuralSchema(gvk schema.GroupVersionKind, structuralSchema []byte) error {
+	node, err := decodeSchemaDocument(structuralSchema)
+	if err != nil {
+		return errors.WrapWithDetails(err, "could not decode structural schema", "gvk", gvk)
+	}
+	r.add(gvk, schemaPatchMeta{node: node})
+	return nil
+}
+
+// AddCustomResourceDefinition registers the schemas of every version of a CustomResourceDefinition,
+// in JSON or YAML format.
+func (r *SchemaRegistry) AddCustomResourceDefinition(crd []byte) error {
+	doc, err := decodeSchemaDocument(crd)
+	if err != nil {
+		return errors.Wrap(err, "could not decode custom resource definition")
+	}
+	spec, _ := doc["spec"].(map[string]interface{})
+	group, _ := spec["group"].(string)
+	names, _ := spec["names"].(map[string]interface{})
+	kind, _ := names["kind"].(string)
+	if kind == "" {
+		return errors.New("custom resource definition has no kind")
+	}
+
+	// The schema can be set for all versions at once in apiextensions.k8s.io/v1beta1.
+	commonSchema := schemaOf(spec["validation"])
+	versions, _ := spec["versions"].([]interface{})
+	if len(versions) == 0 {
+		if version, ok := spec["version"].(string); ok {
+			versions = append(versions, map[string]interface{}{"name": version})
+		}
+	}
+	for _, item := range versions {
+		version, _ := item.(map[string]interface{})
+		name, _ := version["name"].(string)
+		node := schemaOf(version["schema"])
+		if node == nil {
+			node = commonSchema
+		}
+		if name == "" || node == nil {
+			continue
+		}
+		r.add(schema.GroupVersionKind{Group: group, Version: name, Kind: kind}, schemaPatchMeta{node: node})
+	}
+	return nil
+}
+
+// AddOpenAPIDocument registers the definitions of an OpenAPI v2 or v3 document, in JSON or YAML format,
+// that have the x-kubernetes-group-version-kind extension, like the document served by the API server.
+func (r *SchemaRegistry) AddOpenAPIDocument(document []byte) error {
+	doc, err := decodeSchemaDocument(document)
+	if err != nil {
+		return errors.Wrap(err, "could not decode OpenAPI document")
+	}
+	definitions, _ := doc["definitions"].(map[string]interface{})
+	if components, ok := doc["components"].(map[string]interface{}); ok {
+		definitions, _ = components["schemas"].(map[string]interface{})
+	}
+
+	for _, definition := range definitions {
+		node, _ := definition.(map[string]interface{})
+		gvks, _ := node[gvkExtension].([]interface{})
+		for _, item := range gvks {
+			gvk, _ := item.(map[string]interface{})
+			group, _ := gvk["group"].(string)
+			version, _ := gvk["version"].(string)
+			kind, _ := gvk["kind"].(string)
+			r.add(schema.GroupVersionKind{Group: group, Version: version, Kind: kind}, schemaPatchMeta{node: node, definitions: definitions})
+		}
+	}
+	return nil
+}
+
+// LookupPatchMeta returns the patch metadata of a registered kind.
+func (r *SchemaRegistry) LookupPatchMeta(gvk schema.GroupVersionKind) (strategicpatch.LookupPatchMeta, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	lookupPatchMeta, ok := r.schemas[gvk]
+	return lookupPatchMeta, ok
+}
+
+func (r *SchemaRegistry) add(gvk schema.GroupVersionKind, lookupPatchMeta strategicpatch.LookupPatchMeta) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.schemas[gvk] = lookupPatchMeta
+}
+
+func decodeSchemaDocument(data []byte) (map[string]interface{}, error) {
+	// YAMLToJSON accepts JSON as well.
+	data, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func schemaOf(validation interface{}) map[string]interface{} {
+	m, _ := validation.(map[string]interface{})
+	node, _ := m["openAPIV3Schema"].(map[string]interface{})
+	return node
+}
+
+// schemaPatchMeta looks up the patch metadata in a JSON schema. Fields missing
+// from the schema have no patch metadata, which makes their lists replaced.
+type schemaPatchMeta struct {
+	node        map[string]interface{}
+	definitions map[string]interface{}
+}
+
+var _ strategicpatch.LookupPatchMeta = schemaPatchMeta{}
+
+func (s schemaPatchMeta) LookupPatchMetadataForStruct(key string) (strategicpatch.LookupPatchMeta, strategicpatch.PatchMeta, error) {
+	field := s.field(key)
+	patchMeta, err := s.patchMeta(field)
+	if err != nil {
+		return nil, strategicpatch.PatchMeta{}, err
+	}
+	return schemaPatchMeta{node: field, definitions: s.definitions}, patchMeta, nil
+}
+
+func (s schemaPatchMeta) LookupPatchMetadataForSlice(key string) (strategicpatch.LookupPatchMeta, strategicpatch.PatchMeta, error) {
+	field := s.field(key)
+	patchMeta, err := s.patchMeta(field)
+	if err != nil {
+		return nil, strategicpatch.PatchMeta{}, err
+	}
+	items, _ := s.resolve(field)["items"].(map[string]interface{})
+	return schemaPatchMeta{node: items, definitions: s.definitions}, patchMeta, nil
+}
+
+func (s schemaPatchMeta) Name() string {
+	name, _ := s.resolve(s.node)["type"].(string)
+	return name
+}
+
+// field returns the schema of a field of an object, or of the values of a map.
+func (s schemaPatchMeta) field(key string) map[string]interface{} {
+	node := s.resolve(s.node)
+	if properties, ok := node["properties"].(map[string]interface{}); ok {
+		if field, ok := properties[key].(map[string]interface{}); ok {
+			return field
+		}
+	}
+	field, _ := node["additionalProperties"].(map[string]interface{})
+	return field
+}
+
+// resolve follows the references of the schema to the definition they point to.
+func (s schemaPatchMeta) resolve(node map[string]interface{}) map[string]interface{} {
+	// Definitions can reference each other, the depth limit protects against cycles without a type.
+	for depth := 0; node != nil && depth < 32; depth++ {
+		if ref, ok := node["$ref"].(string); ok {
+			name := ref[strings.LastIndex(ref, "/")+1:]
+			node, _ = s.definitions[name].(map[string]interface{})
+			continue
+		}
+		// Fields that only add a description to a referenced type are wrapped in allOf.
+		if allOf, ok := node["allOf"].([]interface{}); ok && len(allOf) == 1 && node["type"] == nil && node["properties"] == nil {
+			node, _ = allOf[0].(map[string]interface{})
+			continue
+		}
+		return node
+	}
+	return node
+}
+
+// patchMeta returns the patch metadata of a field. The extensions of the field
+// take precedence over the extensions of the type it references.
+func (s schemaPatchMeta) patchMeta(field map[string]interface{}) (strategicpatch.PatchMeta, error) {
+	extensions := listPatchExtensions(s.resolve(field))
+	for key, value := range listPatchExtensions(field) {
+		extensions[key] = value
+	}
+	return patchMetaFromExtensions(extensions)
+}
+
+// listPatchExtensions returns the strategic merge patch extensions of a schema,
+// deriving them from the list type extensions if they are not set explicitly.
+func listPatchExtensions(node map[string]interface{}) map[string]interface{} {
+	extensions := map[string]interface{}{}
+	for _, key := range []string{patchStrategyExtension, patchMergeKeyExtension} {
+		if value, ok := node[key]; ok {
+			extensions[key] = value
+		}
+	}
+	if _, ok := extensions[patchStrategyExtension]; ok {
+		return extensions
+	}
+	switch node[listTypeExtension] {
+	case "map":
+		// Strategic merge patch supports a single merge key, lists with compound keys are replaced.
+		if keys, ok := node[listMapKeysExtension].([]interface{}); ok && len(keys) == 1 {
+			extensions[patchStrategyExtension] = "merge"
+			extensions[patchMergeKeyExtension] = keys[0]
+		}
+	case "set":
+		extensions[patchStrategyExtension] = "merge"
+	}
+	return extensions
+}
+
+// patchMetaFromExtensions parses the strategic merge patch extensions of a field. PatchMeta can only
+// be created by the strategicpatch package, so the field is wrapped in a single field OpenAPI kind.
+func patchMetaFromExtensions(extensions map[string]interface{}) (strategicpatch.PatchMeta, error) {
+	kind := &proto.Kind{Fields: map[string]proto.Schema{
+		"": &proto.Arbitrary{BaseSchema: proto.BaseSchema{Extensions: extensions}},
+	}}
+	_, patchMeta, err := strategicpatch.NewPatchMetaFromOpenAPI(kind).LookupPatchMetadataForStruct("")
+	return patchMeta, err
+}
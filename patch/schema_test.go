@@ -0,0 +1,195 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	stdjson "encoding/json"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const testCRD = `
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+    plural: widgets
+  scope: Namespaced
+  versions:
+  - name: v1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              parts:
+                type: array
+                x-kubernetes-list-type: map
+                x-kubernetes-list-map-keys:
+                - name
+                items:
+                  type: object
+                  properties:
+                    name:
+                      type: string
+                    size:
+                      type: integer
+                    color:
+                      type: string
+`
+
+const testOpenAPIDocument = `{
+  "definitions": {
+    "com.example.v1.Widget": {
+      "type": "object",
+      "properties": {
+        "spec": {"$ref": "#/definitions/com.example.v1.WidgetSpec"}
+      },
+      "x-kubernetes-group-version-kind": [{"group": "example.com", "version": "v1", "kind": "Widget"}]
+    },
+    "com.example.v1.WidgetSpec": {
+      "type": "object",
+      "properties": {
+        "parts": {
+          "type": "array",
+          "items": {"$ref": "#/definitions/com.example.v1.Part"},
+          "x-kubernetes-patch-strategy": "merge",
+          "x-kubernetes-patch-merge-key": "name"
+        }
+      }
+    },
+    "com.example.v1.Part": {
+      "type": "object",
+      "properties": {
+        "name": {"type": "string"},
+        "size": {"type": "integer"},
+        "color": {"type": "string"}
+      }
+    }
+  }
+}`
+
+func newTestWidget(parts ...map[string]interface{}) *unstructured.Unstructured {
+	items := make([]interface{}, 0, len(parts))
+	for _, part := range parts {
+		items = append(items, part)
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Widget",
+		"metadata":   map[string]interface{}{"name": "test", "namespace": "default"},
+		"spec":       map[string]interface{}{"parts": items},
+	}}
+}
+
+func TestSchemaRegistry(t *testing.T) {
+	crdRegistry := NewSchemaRegistry()
+	if err := crdRegistry.AddCustomResourceDefinition([]byte(testCRD)); err != nil {
+		t.Fatal(err)
+	}
+	openAPIRegistry := NewSchemaRegistry()
+	if err := openAPIRegistry.AddOpenAPIDocument([]byte(testOpenAPIDocument)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		registry *SchemaRegistry
+	}{
+		{name: "custom resource definition", registry: crdRegistry},
+		{name: "OpenAPI document", registry: openAPIRegistry},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patchMaker := NewPatchMaker(DefaultAnnotator, &K8sStrategicMergePatcher{}, &BaseJSONMergePatcher{}, WithSchemaRegistry(tt.registry), WithChanges())
+
+			modified := newTestWidget(
+				map[string]interface{}{"name": "a", "size": int64(1)},
+				map[string]interface{}{"name": "b", "size": int64(2)},
+			)
+			if err := DefaultAnnotator.SetLastAppliedAnnotation(modified); err != nil {
+				t.Fatal(err)
+			}
+
+			// Fields defaulted by the server in list elements are kept.
+			current := modified.DeepCopy()
+			parts, _, _ := unstructured.NestedSlice(current.Object, "spec", "parts")
+			parts[0].(map[string]interface{})["color"] = "red"
+			if err := unstructured.SetNestedSlice(current.Object, parts, "spec", "parts"); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := patchMaker.Calculate(current, modified)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !result.IsEmpty() {
+				t.Fatalf("Expected empty patch, got %s", result.Patch)
+			}
+
+			result, err = DefaultPatchMaker.Calculate(current, modified)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.IsEmpty() {
+				t.Fatal("Expected the JSON merge patch to replace the list")
+			}
+
+			modified = newTestWidget(
+				map[string]interface{}{"name": "a", "size": int64(3)},
+				map[string]interface{}{"name": "b", "size": int64(2)},
+			)
+			result, err = patchMaker.Calculate(current, modified)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []Change{{Path: "spec.parts[name=a].size", Operation: ChangeReplace, Old: stdjson.Number("1"), New: stdjson.Number("3"), Cause: ChangeCauseLocal}}
+			if len(result.Changes) != 1 || result.Changes[0] != want[0] {
+				t.Fatalf("Expected changes %v, got %v, patch %s", want, result.Changes, result.Patch)
+			}
+
+			// Custom resources do not accept strategic merge patches, the patch must be a plain merge patch.
+			modified = newTestWidget(
+				map[string]interface{}{"name": "b", "size": int64(2)},
+				map[string]interface{}{"name": "c", "size": int64(4)},
+			)
+			result, err = patchMaker.Calculate(current, modified)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(result.Patch), "$") {
+				t.Fatalf("Expected no strategic merge patch directives, got %s", result.Patch)
+			}
+			patched, err := (&BaseJSONMergePatcher{}).MergePatch(result.Current, result.Patch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			parts, _, _ = unstructured.NestedSlice(mustToUnstructured(patched), "spec", "parts")
+			if len(parts) != 2 || parts[0].(map[string]interface{})["name"] != "b" || parts[1].(map[string]interface{})["name"] != "c" {
+				t.Fatalf("Expected the merge patch to remove and add list elements, got %v", parts)
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"emperror.dev/errors"
+	jsonpatch "github.com/evanphx/json-patch"
+	json "github.com/json-iterator/go"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/structured-merge-diff/v4/fieldpath"
+	"sigs.k8s.io/structured-merge-diff/v4/merge"
+	"sigs.k8s.io/structured-merge-diff/v4/typed"
+	"sigs.k8s.io/structured-merge-diff/v4/value"
+)
+
+var (
+	serverSideApplyIgnoredCurrentFields  = mustParseFieldPaths([]string{"metadata.managedFields"})
+	serverSideApplyIgnoredModifiedFields = mustParseFieldPaths([]string{"metadata.managedFields", "status"})
+)
+
+// FieldConflict is a field that the applied configuration would change,
+// while it is owned by another field manager.
+type FieldConflict struct {
+	Manager string
+	// Path of the field in the format of structured-merge-diff, like `.spec.containers[name="app"].image`.
+	Path string
+}
+
+func (c FieldConflict) String() string {
+	return fmt.Sprintf("conflict with %q: %s", c.Manager, c.Path)
+}
+
+// ServerSideApplyMaker is a Maker that calculates what a server-side apply of the modified object
+// by a field manager would change on the current object. It runs the same merge algorithm
+// as the API server does, locally, based on metadata.managedFields of the current object.
+//
+// The Patch of the result is a JSON merge patch between the current object and the outcome of the apply.
+// Fields owned by other managers are listed in the Conflicts of the result. A server-side apply
+// without force would be rejected in that case, the patch shows the outcome of a forced apply.
+type ServerSideApplyMaker struct {
+	manager string
+	schemas map[schema.GroupVersionKind]typed.ParseableType
+	force   bool
+	deduced bool
+}
+
+type ServerSideApplyOption func(*ServerSideApplyMaker)
+
+var _ Maker = &ServerSideApplyMaker{}
+
+// NewServerSideApplyMaker creates a maker that applies the modified objects as the given field manager.
+// Calculate fails for objects of kinds without a registered schema, see WithSchema and WithDeducedSchema.
+func NewServerSideApplyMaker(manager string, opts ...ServerSideApplyOption) *ServerSideApplyMaker {
+	m := &ServerSideApplyMaker{
+		manager: manager,
+		schemas: map[schema.GroupVersionKind]typed.ParseableType{},
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// WithSchema registers the structured-merge-diff schema of a kind,
+// usually one of the types of a typed.Parser created from the OpenAPI definitions.
+func WithSchema(gvk schema.GroupVersionKind, parseableType typed.ParseableType) ServerSideApplyOption {
+	return func(m *ServerSideApplyMaker) {
+		m.schemas[gvk] = parseableType
+	}
+}
+
+// WithDeducedSchema handles objects of kinds without a registered schema with a schema deduced from their content.
+// Every list is atomic in such a schema, so the result and the conflicts differ from the ones of a real
+// server-side apply for lists merged by key, like the containers of a Pod.
+func WithDeducedSchema() ServerSideApplyOption {
+	return func(m *ServerSideApplyMaker) {
+		m.deduced = true
+	}
+}
+
+// WithForceConflicts makes the fields owned by other managers part of the result
+// without reporting them as conflicts, like a forced server-side apply does.
+func WithForceConflicts() ServerSideApplyOption {
+	return func(m *ServerSideApplyMaker) {
+		m.force = true
+	}
+}
+
+func (m *ServerSideApplyMaker) Calculate(currentObject, modifiedObject runtime.Object, opts ...CalculateOption) (*PatchResult, error) {
+	current, err := json.ConfigCompatibleWithStandardLibrary.Marshal(currentObject)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to convert current object to byte sequence")
+	}
+	modified, err := json.ConfigCompatibleWithStandardLibrary.Marshal(modifiedObject)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to convert modified object to byte sequence")
+	}
+
+	for _, opt := range opts {
+		current, modified, err = opt(current, modified)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to apply option function")
+		}
+	}
+
+	// The API server keeps managed fields out of the merge, and ignores the status
+	// of the applied configuration for resources with a status subresource.
+	current, err = deleteFields(current, serverSideApplyIgnoredCurrentFields)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to remove managed fields from current object")
+	}
+	modified, err = deleteFields(modified, serverSideApplyIgnoredModifiedFields)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to remove ignored fields from modified object")
+	}
+
+	current, err = deleteNullInJsonBytes(current)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to delete null from current object")
+	}
+	modified, err = deleteNullInJsonBytes(modified)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to delete null from modified object")
+	}
+
+	accessor, err := meta.Accessor(currentObject)
+	if err != nil {
+		return nil, err
+	}
+	managers, managerNames, err := decodeManagedFields(accessor.GetManagedFields())
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to decode managed fields")
+	}
+
+	gvk := modifiedObject.GetObjectKind().GroupVersionKind()
+	parseableType, ok := m.schemas[gvk]
+	if !ok {
+		if !m.deduced {
+			return nil, errors.NewWithDetails("no schema registered for kind", "gvk", gvk.String())
+		}
+		parseableType = typed.DeducedParseableType
+	}
+	live, err := toTypedValue(parseableType, current)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to convert current object to typed value")
+	}
+	config, err := toTypedValue(parseableType, modified)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to convert modified object to typed value")
+	}
+
+	updater := &merge.Updater{Converter: identityConverter{}}
+	version := fieldpath.APIVersion(gvk.GroupVersion().String())
+
+	var conflicts []FieldConflict
+	if !m.force {
+		_, _, err := updater.Apply(live, config, version, managers.Copy(), m.manager, false)
+		var mergeConflicts merge.Conflicts
+		switch {
+		case errors.As(err, &mergeConflicts):
+			conflicts = toFieldConflicts(mergeConflicts, managerNames)
+		case err != nil:
+			return nil, errors.Wrap(err, "Failed to apply modified object")
+		}
+	}
+
+	applied, _, err := updater.Apply(live, config, version, managers, m.manager, true)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to apply modified object")
+	}
+
+	result := &PatchResult{
+		Patch:     []byte("{}"),
+		Current:   current,
+		Modified:  modified,
+		Conflicts: conflicts,
+
+		kind:      objectKind(currentObject),
+		patchType: types.MergePatchType,
+	}
+
+	result.Original, err = NewManagedFieldsStore(m.manager).GetOriginalConfiguration(currentObject)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get original configuration")
+	}
+
+	// The result of the apply is nil if it leaves the object unchanged.
+	if applied == nil {
+		return result, nil
+	}
+
+	result.patched, err = value.ToJSON(applied.AsValue())
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to convert applied object to byte sequence")
+	}
+	result.Patch, err = jsonpatch.CreateMergePatch(current, result.patched)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create patch between the current and applied object")
+	}
+	if result.IsEmpty() {
+		return result, nil
+	}
+
+	result.Changes, err = calculateChanges(current, result.patched, result.Original, modified, lookupPatchMeta(currentObject), result.getRedactor(), result.kind)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list the changes")
+	}
+
+	return result, nil
+}
+
+func deleteFields(data []byte, paths []fieldPath) ([]byte, error) {
+	var obj map[string]interface{}
+	if err := unmarshalWithNumbers(data, &obj); err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		path.delete(obj)
+	}
+	return json.ConfigCompatibleWithStandardLibrary.Marshal(obj)
+}
+
+func toTypedValue(parseableType typed.ParseableType, data []byte) (*typed.TypedValue, error) {
+	// Decoding the JSON with structured-merge-diff keeps integers apart from floats,
+	// which matters when list elements are identified by numeric keys.
+	v, err := value.FromJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	return typed.AsTyped(v, parseableType.Schema, parseableType.TypeRef)
+}
+
+// decodeManagedFields converts the managed fields of an object to the representation of
+// structured-merge-diff. Managers that apply and update the object are tracked separately,
+// like the API server does, so the names of the managers are returned as well.
+func decodeManagedFields(entries []metav1.ManagedFieldsEntry) (fieldpath.ManagedFields, map[string]string, error) {
+	managers := fieldpath.ManagedFields{}
+	names := map[string]string{}
+	for _, entry := range entries {
+		if entry.FieldsType != fieldsV1Type || entry.FieldsV1 == nil {
+			continue
+		}
+		set := &fieldpath.Set{}
+		if err := set.FromJSON(bytes.NewReader(entry.FieldsV1.Raw)); err != nil {
+			return nil, nil, errors.WrapWithDetails(err, "could not unmarshal managed fields", "manager", entry.Manager)
+		}
+
+		applied := entry.Operation == metav1.ManagedFieldsOperationApply
+		key := entry.Manager
+		if !applied {
+			key = fmt.Sprintf("%s (%s %s)", entry.Manager, entry.Operation, entry.APIVersion)
+		}
+		if existing, ok := managers[key]; ok {
+			set = set.Union(existing.Set())
+		}
+		managers[key] = fieldpath.NewVersionedSet(set, fieldpath.APIVersion(entry.APIVersion), applied)
+		names[key] = entry.Manager
+	}
+	return managers, names, nil
+}
+
+func toFieldConflicts(conflicts merge.Conflicts, managerNames map[string]string) []FieldConflict {
+	result := make([]FieldConflict, 0, len(conflicts))
+	for _, conflict := range conflicts {
+		manager, ok := managerNames[conflict.Manager]
+		if !ok {
+			manager = conflict.Manager
+		}
+		result = append(result, FieldConflict{Manager: manager, Path: conflict.Path.String()})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Manager != result[j].Manager {
+			return result[i].Manager < result[j].Manager
+		}
+		return result[i].Path < result[j].Path
+	})
+	return result
+}
+
+// identityConverter treats the versions of an object as the same, as there are no conversions locally.
+type identityConverter struct{}
+
+func (identityConverter) Convert(object *typed.TypedValue, _ fieldpath.APIVersion) (*typed.TypedValue, error) {
+	return object, nil
+}
+
+func (identityConverter) IsMissingVersionError(error) bool {
+	return false
+}
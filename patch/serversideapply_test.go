@@ -0,0 +1,202 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/structured-merge-diff/v4/typed"
+)
+
+const testPodSchema = `types:
+- name: testPod
+  map:
+    fields:
+    - name: apiVersion
+      type:
+        scalar: string
+    - name: kind
+      type:
+        scalar: string
+    - name: metadata
+      type:
+        namedType: metadata
+    - name: spec
+      type:
+        namedType: spec
+- name: metadata
+  map:
+    fields:
+    - name: name
+      type:
+        scalar: string
+    - name: namespace
+      type:
+        scalar: string
+    - name: labels
+      type:
+        map:
+          elementType:
+            scalar: string
+- name: spec
+  map:
+    fields:
+    - name: containers
+      type:
+        list:
+          elementType:
+            namedType: container
+          elementRelationship: associative
+          keys:
+          - name
+    - name: hostname
+      type:
+        scalar: string
+- name: container
+  map:
+    fields:
+    - name: name
+      type:
+        scalar: string
+    - name: image
+      type:
+        scalar: string
+    - name: args
+      type:
+        list:
+          elementType:
+            scalar: string
+          elementRelationship: atomic
+`
+
+func newServerSideApplyTestMaker(t *testing.T, opts ...ServerSideApplyOption) *ServerSideApplyMaker {
+	parser, err := typed.NewParser(typed.YAMLObject(testPodSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	return NewServerSideApplyMaker("operator", append([]ServerSideApplyOption{WithSchema(gvk, parser.Type("testPod"))}, opts...)...)
+}
+
+func newServerSideApplyTestPod() *testPod {
+	current := newTestPod(
+		testContainer{Name: "app", Image: "app:1"},
+		testContainer{Name: "sidecar", Image: "sidecar:1"},
+	)
+	current.Spec.Hostname = "test"
+	current.SetManagedFields([]metav1.ManagedFieldsEntry{
+		{
+			Manager:    "operator",
+			Operation:  metav1.ManagedFieldsOperationApply,
+			APIVersion: "v1",
+			FieldsType: "FieldsV1",
+			FieldsV1: &metav1.FieldsV1{Raw: []byte(`{
+				"f:spec":{"f:hostname":{},"f:containers":{"k:{\"name\":\"app\"}":{".":{},"f:name":{},"f:image":{}}}}
+			}`)},
+		},
+		{
+			Manager:    "kubectl",
+			Operation:  metav1.ManagedFieldsOperationUpdate,
+			APIVersion: "v1",
+			FieldsType: "FieldsV1",
+			FieldsV1: &metav1.FieldsV1{Raw: []byte(`{
+				"f:spec":{"f:containers":{"k:{\"name\":\"sidecar\"}":{".":{},"f:name":{},"f:image":{}}}}
+			}`)},
+		},
+	})
+	return current
+}
+
+func TestServerSideApplyMaker(t *testing.T) {
+	maker := newServerSideApplyTestMaker(t)
+	current := newServerSideApplyTestPod()
+
+	modified := newTestPod(testContainer{Name: "app", Image: "app:1"})
+	modified.Spec.Hostname = "test"
+	result, err := maker.Calculate(current, modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.IsEmpty() || len(result.Conflicts) != 0 {
+		t.Fatalf("Expected a no-op apply, got %s, conflicts %v", result.Patch, result.Conflicts)
+	}
+
+	// Fields owned by the manager but left out of the applied configuration are removed.
+	modified = newTestPod(testContainer{Name: "app", Image: "app:2"})
+	result, err = maker.Calculate(current, modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantChanges := []Change{
+		{Path: "spec.containers[name=app].image", Operation: ChangeReplace, Old: "app:1", New: "app:2", Cause: ChangeCauseLocal},
+		{Path: "spec.hostname", Operation: ChangeRemove, Old: "test", Cause: ChangeCauseLocal},
+	}
+	if !reflect.DeepEqual(result.Changes, wantChanges) {
+		t.Fatalf("Expected changes %v, got %v", wantChanges, result.Changes)
+	}
+	if len(result.Conflicts) != 0 {
+		t.Fatalf("Expected no conflicts, got %v", result.Conflicts)
+	}
+}
+
+func TestServerSideApplyMakerConflicts(t *testing.T) {
+	current := newServerSideApplyTestPod()
+	modified := newTestPod(
+		testContainer{Name: "app", Image: "app:1"},
+		testContainer{Name: "sidecar", Image: "sidecar:2"},
+	)
+	modified.Spec.Hostname = "test"
+
+	result, err := newServerSideApplyTestMaker(t).Calculate(current, modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantConflicts := []FieldConflict{{Manager: "kubectl", Path: `.spec.containers[name="sidecar"].image`}}
+	if !reflect.DeepEqual(result.Conflicts, wantConflicts) {
+		t.Fatalf("Expected conflicts %v, got %v", wantConflicts, result.Conflicts)
+	}
+	if result.IsEmpty() {
+		t.Fatal("Expected the patch of a forced apply")
+	}
+
+	result, err = newServerSideApplyTestMaker(t, WithForceConflicts()).Calculate(current, modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Conflicts) != 0 || result.IsEmpty() {
+		t.Fatalf("Expected a forced apply without conflicts, got %s, conflicts %v", result.Patch, result.Conflicts)
+	}
+}
+
+func TestServerSideApplyMakerWithoutSchema(t *testing.T) {
+	current := newServerSideApplyTestPod()
+	modified := newTestPod(testContainer{Name: "app", Image: "app:2"})
+	modified.Spec.Hostname = "test"
+
+	if _, err := NewServerSideApplyMaker("operator").Calculate(current, modified); err == nil {
+		t.Fatal("Expected an error for a kind without a schema")
+	}
+
+	result, err := NewServerSideApplyMaker("operator", WithDeducedSchema()).Calculate(current, modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.IsEmpty() {
+		t.Fatal("Expected a patch with the deduced schema")
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// Names of the traced stages of the calculation, see WithTrace.
+const (
+	TraceStageConfigHash        = "config hash fast path"
+	TraceStageMarshal           = "marshal"
+	TraceStageOption            = "option"
+	TraceStageDeleteNulls       = "delete nulls"
+	TraceStageOriginal          = "original"
+	TraceStageThreeWayPatch     = "three-way patch"
+	TraceStageTwoWayPatch       = "two-way patch"
+	TraceStageVerificationPatch = "verification patch"
+	TraceStageFinalPatch        = "final patch"
+)
+
+// Trace records the objects after each stage of a calculation, see WithTrace.
+type Trace struct {
+	Stages []TraceStage
+}
+
+// TraceStage holds the objects a stage of the calculation produced. Objects the stage did not change are nil.
+type TraceStage struct {
+	// Name of the stage, one of the TraceStage constants.
+	Name string
+	// Detail tells more about the stage, like the name of the function of an option.
+	Detail string
+
+	Current  []byte
+	Modified []byte
+	Original []byte
+	Patch    []byte
+	// Patched is the current object with the patch applied.
+	Patched []byte
+}
+
+// WithTrace makes the patch maker record the objects after each stage of the calculation in PatchResult.Trace.
+// Meant for debugging objects that never match, as the trace holds several copies of the objects.
+func WithTrace() PatchMakerOption {
+	return func(p *PatchMaker) {
+		p.trace = true
+	}
+}
+
+// record appends a stage to the trace, if there is one.
+func (t *Trace) record(stage TraceStage) {
+	if t == nil {
+		return
+	}
+	t.Stages = append(t.Stages, stage)
+}
+
+// newTrace returns an empty trace if tracing is enabled, nil otherwise.
+func (p *PatchMaker) newTrace() *Trace {
+	if !p.trace {
+		return nil
+	}
+	return &Trace{}
+}
+
+// optionName returns the name of the function of a CalculateOption, like "patch.IgnoreStatusFields.func1".
+func optionName(opt CalculateOption) string {
+	f := runtime.FuncForPC(reflect.ValueOf(opt).Pointer())
+	if f == nil {
+		return ""
+	}
+	name := f.Name()
+	return name[strings.LastIndex(name, "/")+1:]
+}
+
+// Explain formats the trace of the calculation with sensitive values redacted, see WithTrace and WithRedactor.
+func (p *PatchResult) Explain() string {
+	if p.Trace == nil {
+		return "no trace recorded, see WithTrace\n"
+	}
+
+	var b strings.Builder
+	for i, stage := range p.Trace.Stages {
+		if stage.Detail != "" {
+			fmt.Fprintf(&b, "%d. %s (%s)\n", i+1, stage.Name, stage.Detail)
+		} else {
+			fmt.Fprintf(&b, "%d. %s\n", i+1, stage.Name)
+		}
+		for _, object := range []struct {
+			name string
+			data []byte
+		}{
+			{"Current", stage.Current},
+			{"Modified", stage.Modified},
+			{"Original", stage.Original},
+			{"Patch", stage.Patch},
+			{"Patched", stage.Patched},
+		} {
+			if object.data != nil {
+				fmt.Fprintf(&b, "   %s: %s\n", object.name, p.redacted(object.data))
+			}
+		}
+	}
+	return b.String()
+}
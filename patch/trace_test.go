@@ -0,0 +1,83 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	current := newTestPod(testContainer{Name: "app", Image: "app:1"})
+	if err := DefaultAnnotator.SetLastAppliedAnnotation(current); err != nil {
+		t.Fatal(err)
+	}
+	modified := newTestPod(testContainer{Name: "app", Image: "app:2"})
+
+	result, err := DefaultPatchMaker.Calculate(current, modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Trace != nil {
+		t.Fatal("Expected no trace without WithTrace")
+	}
+
+	patchMaker := NewPatchMaker(DefaultAnnotator, &K8sStrategicMergePatcher{}, &BaseJSONMergePatcher{}, WithTrace())
+	result, err = patchMaker.Calculate(current, modified, IgnoreField("status"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, stage := range result.Trace.Stages {
+		names = append(names, stage.Name)
+	}
+	want := []string{
+		TraceStageMarshal,
+		TraceStageOption,
+		TraceStageDeleteNulls,
+		TraceStageOriginal,
+		TraceStageThreeWayPatch,
+		TraceStageVerificationPatch,
+		TraceStageFinalPatch,
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("Expected stages %v, got %v", want, names)
+	}
+	if detail := result.Trace.Stages[1].Detail; !strings.HasPrefix(detail, "patch.IgnoreField") {
+		t.Fatalf("Expected the option to be named after its function, got %q", detail)
+	}
+	if stage := result.Trace.Stages[3]; string(stage.Original) != string(result.Original) {
+		t.Fatalf("Expected the original in the trace, got %s", stage.Original)
+	}
+	if stage := result.Trace.Stages[6]; string(stage.Patch) != string(result.Patch) {
+		t.Fatalf("Expected the final patch in the trace, got %s", stage.Patch)
+	}
+
+	explained := result.Explain()
+	if !strings.Contains(explained, "7. final patch") || !strings.Contains(explained, `"image":"app:2"`) {
+		t.Fatalf("Unexpected explanation:\n%s", explained)
+	}
+
+	// The final stage is recorded for empty patches too.
+	result, err = patchMaker.Calculate(current, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := result.Trace.Stages[len(result.Trace.Stages)-1]
+	if last.Name != TraceStageFinalPatch || string(last.Patch) != "{}" {
+		t.Fatalf("Expected the empty final patch to be traced, got %+v", last)
+	}
+}
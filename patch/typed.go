@@ -0,0 +1,88 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"reflect"
+
+	"emperror.dev/errors"
+	jsonpatch "github.com/evanphx/json-patch"
+	json "github.com/json-iterator/go"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+// TypedPatchResult is a PatchResult with the patched object decoded to the type of the compared objects.
+type TypedPatchResult[T runtime.Object] struct {
+	*PatchResult
+
+	// Patched is the current object with the patch applied, a copy of the current object if the patch is empty.
+	Patched T
+}
+
+// CalculateTyped is like Maker.Calculate, but also returns the object the patch would result in,
+// without type assertions on the side of the caller. T is a pointer type, like *appsv1.Deployment.
+// The patch is applied to the current object as it is, so fields removed by options, like the status, are kept.
+func CalculateTyped[T runtime.Object](maker Maker, current, modified T, opts ...CalculateOption) (*TypedPatchResult[T], error) {
+	result, err := maker.Calculate(current, modified, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	t := reflect.TypeOf(current)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return nil, errors.Errorf("type %T is not a pointer", current)
+	}
+
+	patched, err := json.ConfigCompatibleWithStandardLibrary.Marshal(current)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to convert current object to byte sequence")
+	}
+	if !result.IsEmpty() {
+		patched, err = applyResultPatch(maker, current, patched, result)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to apply patch to current object")
+		}
+	}
+
+	typedResult := &TypedPatchResult[T]{PatchResult: result, Patched: reflect.New(t.Elem()).Interface().(T)}
+	if err := json.Unmarshal(patched, typedResult.Patched); err != nil {
+		return nil, errors.WrapWithDetails(err, "Failed to decode patched object", "type", t.String())
+	}
+	return typedResult, nil
+}
+
+// applyResultPatch applies the patch of the result to the current object the same way the maker does.
+func applyResultPatch(maker Maker, currentObject runtime.Object, current []byte, result *PatchResult) ([]byte, error) {
+	if cachingMaker, ok := maker.(*CachingMaker); ok {
+		maker = cachingMaker.maker
+	}
+	if p, ok := maker.(*PatchMaker); ok {
+		// The objects are left unstructured by the maker if the conversion loses fields, the patch type tells.
+		if result.PatchType() == types.StrategicMergePatchType {
+			var err error
+			currentObject, _, err = p.convertToTyped(currentObject)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return p.applyPatch(currentObject, current, result.Patch)
+	}
+	if result.PatchType() == types.StrategicMergePatchType {
+		return strategicpatch.StrategicMergePatch(current, result.Patch, currentObject)
+	}
+	return jsonpatch.MergePatch(current, result.Patch)
+}
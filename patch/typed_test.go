@@ -0,0 +1,86 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestCalculateTyped(t *testing.T) {
+	current := newTestPod(
+		testContainer{Name: "app", Image: "app:1"},
+		testContainer{Name: "sidecar", Image: "sidecar:1"},
+	)
+	if err := DefaultAnnotator.SetLastAppliedAnnotation(current); err != nil {
+		t.Fatal(err)
+	}
+	current.Spec.Containers[0].Args = []string{"--defaulted"}
+	modified := newTestPod(
+		testContainer{Name: "app", Image: "app:2"},
+		testContainer{Name: "sidecar", Image: "sidecar:1"},
+	)
+
+	result, err := CalculateTyped(DefaultPatchMaker, current, modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []testContainer{
+		{Name: "app", Image: "app:2", Args: []string{"--defaulted"}},
+		{Name: "sidecar", Image: "sidecar:1"},
+	}
+	if !reflect.DeepEqual(result.Patched.Spec.Containers, want) {
+		t.Fatalf("Expected patched containers %v, got %v", want, result.Patched.Spec.Containers)
+	}
+	if result.IsEmpty() {
+		t.Fatal("Expected a non-empty patch")
+	}
+
+	result, err = CalculateTyped(DefaultPatchMaker, current, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Patched == current || !reflect.DeepEqual(result.Patched, current) {
+		t.Fatal("Expected a copy of current for an empty patch")
+	}
+
+	// Fields removed by options are kept in the patched object, whether the patch is empty or not.
+	current.Labels = map[string]string{"app": "test"}
+	for _, m := range []*testPod{modified, current} {
+		result, err = CalculateTyped(DefaultPatchMaker, current, m, IgnoreField("metadata"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Patched.Name != "test" || result.Patched.Labels["app"] != "test" {
+			t.Fatalf("Expected the ignored metadata to be kept, got %v", result.Patched.ObjectMeta)
+		}
+	}
+	if result.Patched.Spec.Containers[0].Image != "app:1" {
+		t.Fatalf("Expected an unchanged object, got %v", result.Patched.Spec.Containers)
+	}
+
+	unstructuredResult, err := CalculateTyped(DefaultPatchMaker,
+		&unstructured.Unstructured{Object: mustToUnstructured(mustMarshal(current))},
+		&unstructured.Unstructured{Object: mustToUnstructured(mustMarshal(modified))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	containers, _, _ := unstructured.NestedSlice(unstructuredResult.Patched.Object, "spec", "containers")
+	if len(containers) != 2 || containers[0].(map[string]interface{})["image"] != "app:2" {
+		t.Fatalf("Unexpected patched containers %v", containers)
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"emperror.dev/errors"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+// UnreconciledFieldsError is returned by patch makers created with WithVerification
+// when the current object with the patch applied still differs from the modified object.
+type UnreconciledFieldsError struct {
+	// Paths of the fields that differ, like "spec.template.spec.containers[name=app].image".
+	Paths []string
+}
+
+func (e *UnreconciledFieldsError) Error() string {
+	return fmt.Sprintf("patch does not reconcile fields: %s", strings.Join(e.Paths, ", "))
+}
+
+// WithVerification makes the patch maker apply the calculated patch to the current object and check
+// that every field of the modified object, after the options and null deletion, has the modified value.
+// The calculation fails with an UnreconciledFieldsError otherwise. Results of the config hash fast path are not verified.
+func WithVerification() PatchMakerOption {
+	return func(p *PatchMaker) {
+		p.verification = true
+	}
+}
+
+// verifyPatched checks that the patched object contains every field of the modified object.
+// Fields of the patched object missing from the modified object, like defaults, are not checked.
+func verifyPatched(patched, modified []byte, lookupPatchMeta strategicpatch.LookupPatchMeta) error {
+	var patchedMap, modifiedMap map[string]interface{}
+	if err := unmarshalWithNumbers(patched, &patchedMap); err != nil {
+		return errors.Wrap(err, "could not unmarshal patched object")
+	}
+	if err := unmarshalWithNumbers(modified, &modifiedMap); err != nil {
+		return errors.Wrap(err, "could not unmarshal modified object")
+	}
+
+	var paths []string
+	verifyMap("", modifiedMap, patchedMap, lookupPatchMeta, &paths)
+	if len(paths) > 0 {
+		return errors.WithStack(&UnreconciledFieldsError{Paths: paths})
+	}
+	return nil
+}
+
+func verifyMap(path string, modified, patched map[string]interface{}, lookupPatchMeta strategicpatch.LookupPatchMeta, paths *[]string) {
+	keys := make([]string, 0, len(modified))
+	for key := range modified {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		modifiedValue := modified[key]
+		fieldPath := joinChangePath(path, key)
+		patchedValue, ok := patched[key]
+		if !ok {
+			// Empty objects are kept by DeleteNullInJson, but a missing field is just as empty.
+			if m, isMap := modifiedValue.(map[string]interface{}); !isMap || len(m) > 0 {
+				*paths = append(*paths, fieldPath)
+			}
+			continue
+		}
+		verifyValue(fieldPath, key, modifiedValue, patchedValue, lookupPatchMeta, paths)
+	}
+}
+
+func verifyValue(path, key string, modified, patched interface{}, lookupPatchMeta strategicpatch.LookupPatchMeta, paths *[]string) {
+	if reflect.DeepEqual(modified, patched) {
+		return
+	}
+
+	switch modifiedValue := modified.(type) {
+	case map[string]interface{}:
+		if patchedValue, ok := patched.(map[string]interface{}); ok {
+			verifyMap(path, modifiedValue, patchedValue, lookupStructPatchMeta(lookupPatchMeta, key), paths)
+			return
+		}
+	case []interface{}:
+		if patchedValue, ok := patched.([]interface{}); ok {
+			verifyList(path, key, modifiedValue, patchedValue, lookupPatchMeta, paths)
+			return
+		}
+	}
+
+	*paths = append(*paths, path)
+}
+
+// verifyList checks the elements of a list. Merged lists can have additional elements in the patched object,
+// the elements of other lists are compared by index.
+func verifyList(path, key string, modified, patched []interface{}, lookupPatchMeta strategicpatch.LookupPatchMeta, paths *[]string) {
+	elementPatchMeta, mergeKey, merge := lookupSliceMergeMeta(lookupPatchMeta, key)
+
+	switch {
+	case mergeKey != "" && isListOfMaps(modified) && isListOfMaps(patched) && hasMergeKey(modified, mergeKey) && hasMergeKey(patched, mergeKey):
+		for _, item := range modified {
+			modifiedElement := item.(map[string]interface{})
+			elementPath := fmt.Sprintf("%s[%s=%v]", path, mergeKey, modifiedElement[mergeKey])
+			patchedElement := findElement(patched, mergeKey, modifiedElement[mergeKey])
+			if patchedElement == nil {
+				*paths = append(*paths, elementPath)
+				continue
+			}
+			verifyMap(elementPath, modifiedElement, patchedElement, elementPatchMeta, paths)
+		}
+	case merge && !isListOfMaps(modified):
+		for i, item := range modified {
+			if !containsValue(patched, item) {
+				*paths = append(*paths, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	case len(modified) == len(patched) && isListOfMaps(modified) && isListOfMaps(patched):
+		for i := range modified {
+			verifyMap(fmt.Sprintf("%s[%d]", path, i), modified[i].(map[string]interface{}), patched[i].(map[string]interface{}), elementPatchMeta, paths)
+		}
+	default:
+		*paths = append(*paths, path)
+	}
+}
+
+// lookupSliceMergeMeta is like lookupSlicePatchMeta, but also tells whether the list is merged.
+func lookupSliceMergeMeta(lookupPatchMeta strategicpatch.LookupPatchMeta, key string) (strategicpatch.LookupPatchMeta, string, bool) {
+	if lookupPatchMeta == nil {
+		return nil, "", false
+	}
+	elementPatchMeta, patchMeta, err := lookupPatchMeta.LookupPatchMetadataForSlice(key)
+	if err != nil {
+		return nil, "", false
+	}
+	merge := false
+	for _, strategy := range patchMeta.GetPatchStrategies() {
+		if strategy == "merge" {
+			merge = true
+		}
+	}
+	return elementPatchMeta, patchMeta.GetPatchMergeKey(), merge
+}
+
+func findElement(list []interface{}, mergeKey string, keyValue interface{}) map[string]interface{} {
+	for _, item := range list {
+		element := item.(map[string]interface{})
+		if reflect.DeepEqual(element[mergeKey], keyValue) {
+			return element
+		}
+	}
+	return nil
+}
+
+func containsValue(list []interface{}, value interface{}) bool {
+	for _, item := range list {
+		if reflect.DeepEqual(item, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"reflect"
+	"testing"
+
+	"emperror.dev/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// lossyStrategicMergePatcher ignores the patches it is asked to apply.
+type lossyStrategicMergePatcher struct {
+	K8sStrategicMergePatcher
+}
+
+func (p *lossyStrategicMergePatcher) StrategicMergePatch(original, patch []byte, dataStruct interface{}) ([]byte, error) {
+	return original, nil
+}
+
+func TestVerification(t *testing.T) {
+	newCurrent := func() *testPod {
+		current := newTestPod(
+			testContainer{Name: "app", Image: "app:1"},
+			testContainer{Name: "sidecar", Image: "sidecar:1"},
+		)
+		current.Finalizers = []string{"defaulted"}
+		current.Spec.Containers[1].Args = []string{"--defaulted"}
+		return current
+	}
+	modified := newTestPod(
+		testContainer{Name: "sidecar", Image: "sidecar:1"},
+		testContainer{Name: "app", Image: "app:2"},
+	)
+	modified.Finalizers = []string{"operator"}
+
+	patchMaker := NewPatchMaker(DefaultAnnotator, &K8sStrategicMergePatcher{}, &BaseJSONMergePatcher{}, WithVerification())
+	result, err := patchMaker.Calculate(newCurrent(), modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.IsEmpty() {
+		t.Fatal("Expected a non-empty patch")
+	}
+
+	unstructuredCurrent := &unstructured.Unstructured{Object: mustToUnstructured(mustMarshal(newCurrent()))}
+	unstructuredModified := &unstructured.Unstructured{Object: mustToUnstructured(mustMarshal(modified))}
+	if _, err := patchMaker.Calculate(unstructuredCurrent, unstructuredModified); err != nil {
+		t.Fatal(err)
+	}
+
+	lossyPatchMaker := NewPatchMaker(DefaultAnnotator, &lossyStrategicMergePatcher{}, &BaseJSONMergePatcher{}, WithVerification())
+	_, err = lossyPatchMaker.Calculate(newCurrent(), modified)
+	var unreconciled *UnreconciledFieldsError
+	if !errors.As(err, &unreconciled) {
+		t.Fatalf("Expected an UnreconciledFieldsError, got %v", err)
+	}
+	want := []string{"metadata.finalizers[0]", "spec.containers[name=app].image"}
+	if !reflect.DeepEqual(unreconciled.Paths, want) {
+		t.Fatalf("Expected unreconciled paths %v, got %v", want, unreconciled.Paths)
+	}
+
+	if _, err := lossyPatchMaker.Calculate(modified, modified); err != nil {
+		t.Fatalf("Expected identical objects to verify, got %v", err)
+	}
+}
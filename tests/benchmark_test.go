@@ -0,0 +1,117 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"fmt"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/cisco-open/k8s-objectmatcher/patch"
+)
+
+func benchmarkPodSpec(image string) v1.PodTemplateSpec {
+	var containers []v1.Container
+	for i := 0; i < 4; i++ {
+		containers = append(containers, v1.Container{
+			Name:  fmt.Sprintf("container-%d", i),
+			Image: image,
+			Ports: []v1.ContainerPort{{Name: "http", ContainerPort: 8080}, {Name: "metrics", ContainerPort: 9090}},
+			Env:   []v1.EnvVar{{Name: "A", Value: "a"}, {Name: "B", Value: "b"}, {Name: "C", Value: "c"}},
+			VolumeMounts: []v1.VolumeMount{
+				{Name: "config", MountPath: "/etc/config"},
+				{Name: "data", MountPath: "/data"},
+			},
+		})
+	}
+	return v1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "benchmark"}},
+		Spec: v1.PodSpec{
+			Containers: containers,
+			Volumes: []v1.Volume{
+				{Name: "config", VolumeSource: v1.VolumeSource{ConfigMap: &v1.ConfigMapVolumeSource{LocalObjectReference: v1.LocalObjectReference{Name: "config"}}}},
+			},
+		},
+	}
+}
+
+func benchmarkDeployment(image string) runtime.Object {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "benchmark", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: int32ref(3),
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "benchmark"}},
+			Template: benchmarkPodSpec(image),
+		},
+	}
+}
+
+func benchmarkStatefulSet(image string) runtime.Object {
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "benchmark", Namespace: "default"},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: int32ref(3),
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "benchmark"}},
+			Template: benchmarkPodSpec(image),
+			VolumeClaimTemplates: []v1.PersistentVolumeClaim{
+				{ObjectMeta: metav1.ObjectMeta{Name: "data"}},
+			},
+		},
+	}
+}
+
+func benchmarkCalculate(b *testing.B, newObject func(image string) runtime.Object, opts ...patch.PatchMakerOption) {
+	patchMaker := patch.NewPatchMaker(patch.DefaultAnnotator, &patch.K8sStrategicMergePatcher{}, &patch.BaseJSONMergePatcher{}, opts...)
+
+	current := newObject("image:1")
+	if err := patch.DefaultAnnotator.SetLastAppliedAnnotation(current); err != nil {
+		b.Fatal(err)
+	}
+	modified := newObject("image:2")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		result, err := patchMaker.Calculate(current, modified)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if result.IsEmpty() {
+			b.Fatal("Expected a non-empty patch")
+		}
+	}
+}
+
+func BenchmarkCalculateDeployment(b *testing.B) {
+	b.Run("patch", func(b *testing.B) {
+		benchmarkCalculate(b, benchmarkDeployment)
+	})
+	b.Run("changes", func(b *testing.B) {
+		benchmarkCalculate(b, benchmarkDeployment, patch.WithChanges(), patch.WithVerification())
+	})
+}
+
+func BenchmarkCalculateStatefulSet(b *testing.B) {
+	b.Run("patch", func(b *testing.B) {
+		benchmarkCalculate(b, benchmarkStatefulSet)
+	})
+	b.Run("changes", func(b *testing.B) {
+		benchmarkCalculate(b, benchmarkStatefulSet, patch.WithChanges(), patch.WithVerification())
+	})
+}
@@ -0,0 +1,68 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"github.com/cisco-open/k8s-objectmatcher/patch"
+)
+
+func TestWithSchemeDeployment(t *testing.T) {
+	newDeployment := func(image string) *appsv1.Deployment {
+		deployment := benchmarkDeployment(image).(*appsv1.Deployment)
+		deployment.APIVersion = "apps/v1"
+		deployment.Kind = "Deployment"
+		container := &deployment.Spec.Template.Spec.Containers[0]
+		container.Resources.Limits = v1.ResourceList{v1.ResourceMemory: resource.MustParse("128Mi")}
+		container.ReadinessProbe = &v1.Probe{Handler: v1.Handler{HTTPGet: &v1.HTTPGetAction{Path: "/ready", Port: intstr.FromString("http")}}}
+		return deployment
+	}
+	current := newDeployment("app:1")
+	if err := patch.DefaultAnnotator.SetLastAppliedAnnotation(current); err != nil {
+		t.Fatal(err)
+	}
+	modified := newDeployment("app:2")
+
+	typedResult, err := patch.DefaultPatchMaker.Calculate(current, modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	toUnstructured := func(obj runtime.Object) *unstructured.Unstructured {
+		u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &unstructured.Unstructured{Object: u}
+	}
+	schemePatchMaker := patch.NewPatchMaker(patch.DefaultAnnotator, &patch.K8sStrategicMergePatcher{}, &patch.BaseJSONMergePatcher{}, patch.WithScheme(scheme.Scheme))
+	result, err := schemePatchMaker.Calculate(toUnstructured(current), toUnstructured(modified))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.PatchType() != types.StrategicMergePatchType || string(result.Patch) != string(typedResult.Patch) {
+		t.Fatalf("Expected the patch of the typed objects %s, got %s %s", typedResult.Patch, result.PatchType(), result.Patch)
+	}
+}
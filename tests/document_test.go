@@ -0,0 +1,101 @@
+// Copyright © 2026 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/yaml"
+
+	"github.com/cisco-open/k8s-objectmatcher/patch"
+)
+
+func TestCalculateBytesTypedDeployment(t *testing.T) {
+	newDeployment := func(replicas int32, image string) *appsv1.Deployment {
+		deployment := &appsv1.Deployment{}
+		deployment.APIVersion = "apps/v1"
+		deployment.Kind = "Deployment"
+		deployment.Name = "test"
+		deployment.Namespace = "default"
+		deployment.Spec.Replicas = &replicas
+		deployment.Spec.Template = benchmarkPodSpec(image)
+		return deployment
+	}
+	current := newDeployment(3, "app:1")
+	if err := patch.DefaultAnnotator.SetLastAppliedAnnotation(current); err != nil {
+		t.Fatal(err)
+	}
+	current.Spec.Template.Spec.Containers[0].Ports = append(current.Spec.Template.Spec.Containers[0].Ports, v1.ContainerPort{Name: "debug", ContainerPort: 2345})
+	modified := newDeployment(5, "app:2")
+
+	typedResult, err := patch.DefaultPatchMaker.Calculate(current, modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typedResult.IsEmpty() {
+		t.Fatal("Expected a patch for the typed objects")
+	}
+
+	currentYAML, err := yaml.Marshal(current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modifiedYAML, err := yaml.Marshal(modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	currentMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modifiedMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schemePatchMaker := patch.NewPatchMaker(patch.DefaultAnnotator, &patch.K8sStrategicMergePatcher{}, &patch.BaseJSONMergePatcher{}, patch.WithScheme(scheme.Scheme)).(*patch.PatchMaker)
+	tests := []struct {
+		name       string
+		patchMaker *patch.PatchMaker
+		hint       patch.TypeHint
+	}{
+		{name: "Go type", patchMaker: patch.DefaultPatchMaker.(*patch.PatchMaker), hint: patch.TypeHint{Object: &appsv1.Deployment{}}},
+		{name: "registered kind", patchMaker: schemePatchMaker, hint: patch.TypeHint{GroupVersionKind: appsv1.SchemeGroupVersion.WithKind("Deployment")}},
+		{name: "kind of the document", patchMaker: schemePatchMaker},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.patchMaker.CalculateBytes(currentYAML, modifiedYAML, tt.hint)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(result.Patch) != string(typedResult.Patch) {
+				t.Fatalf("Expected the patch of the typed objects %s, got %s", typedResult.Patch, result.Patch)
+			}
+
+			mapResult, err := tt.patchMaker.CalculateMap(currentMap, modifiedMap, tt.hint)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(mapResult.Patch) != string(typedResult.Patch) {
+				t.Fatalf("Expected the patch of the typed objects %s, got %s", typedResult.Patch, mapResult.Patch)
+			}
+		})
+	}
+}